@@ -0,0 +1,84 @@
+package deque
+
+import (
+	"math/bits"
+	"reflect"
+	"sync"
+)
+
+// Allocator provides the buffers used by a Deque, as set by
+// [Deque.SetAllocator]. An Allocator shared by multiple deques must be safe for
+// concurrent use if the deques are used concurrently.
+type Allocator[T any] interface {
+	// Alloc returns a slice of length n with every item set to the zero value.
+	// A Deque only requests lengths that are powers of two.
+	Alloc(n int) []T
+	// Free is called with a buffer that the Deque no longer uses. The buffer
+	// may still hold copies of items, so an Allocator that keeps the buffer
+	// for reuse must clear it.
+	Free(buf []T)
+}
+
+// pools holds the allocator returned by PoolAllocator for each item type.
+var pools sync.Map
+
+// PoolAllocator returns an [Allocator] that keeps freed buffers in a
+// [sync.Pool] for each power of two size, so that a buffer discarded when one
+// Deque resizes can be reused by any Deque with the same item type. All calls
+// with the same type argument return the same Allocator, which is safe for
+// concurrent use.
+//
+//	var q deque.Deque[int]
+//	q.SetAllocator(deque.PoolAllocator[int]())
+//
+// As with any sync.Pool, buffers that are not reused may be released by the
+// garbage collector.
+func PoolAllocator[T any]() Allocator[T] {
+	t := reflect.TypeFor[T]()
+	a, ok := pools.Load(t)
+	if !ok {
+		a, _ = pools.LoadOrStore(t, new(poolAllocator[T]))
+	}
+	return a.(*poolAllocator[T])
+}
+
+type poolAllocator[T any] struct {
+	// sizes holds freed buffers of length 1<<i in sizes[i].
+	sizes [bits.UintSize]sync.Pool
+	// boxes holds unused poolBufs, so that putting a buffer into a pool does
+	// not allocate.
+	boxes sync.Pool
+}
+
+// poolBuf holds a buffer in a pool.
+type poolBuf[T any] struct {
+	buf []T
+}
+
+func (a *poolAllocator[T]) Alloc(n int) []T {
+	if n <= 0 || n&(n-1) != 0 {
+		return make([]T, n)
+	}
+	b, ok := a.sizes[bits.TrailingZeros(uint(n))].Get().(*poolBuf[T])
+	if !ok {
+		return make([]T, n)
+	}
+	buf := b.buf
+	b.buf = nil
+	a.boxes.Put(b)
+	return buf
+}
+
+func (a *poolAllocator[T]) Free(buf []T) {
+	n := len(buf)
+	if n == 0 || n&(n-1) != 0 {
+		return
+	}
+	clear(buf)
+	b, ok := a.boxes.Get().(*poolBuf[T])
+	if !ok {
+		b = new(poolBuf[T])
+	}
+	b.buf = buf
+	a.sizes[bits.TrailingZeros(uint(n))].Put(b)
+}
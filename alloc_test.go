@@ -0,0 +1,150 @@
+package deque
+
+import (
+	"slices"
+	"testing"
+)
+
+// countingAllocator records the buffers that a Deque allocates and frees.
+type countingAllocator[T any] struct {
+	live  map[*T]int
+	freed int
+}
+
+func (a *countingAllocator[T]) Alloc(n int) []T {
+	buf := make([]T, n)
+	if a.live == nil {
+		a.live = make(map[*T]int)
+	}
+	a.live[&buf[0]] = n
+	return buf
+}
+
+func (a *countingAllocator[T]) Free(buf []T) {
+	if a.live[&buf[0]] != len(buf) {
+		panic("freed a buffer that was not allocated")
+	}
+	delete(a.live, &buf[0])
+	a.freed++
+}
+
+func TestAllocator(t *testing.T) {
+	for _, incremental := range []bool{false, true} {
+		var a countingAllocator[int]
+		var q Deque[int]
+		q.SetAllocator(&a)
+		q.SetIncrementalResize(incremental)
+		for i := range 1000 {
+			q.PushBack(i)
+		}
+		for range 990 {
+			q.PopFront()
+		}
+		q.Grow(100)
+		q.CopyInSlice(make([]int, 300))
+		q.PopBack()
+		q.Clear()
+		q.PushFront(1)
+		if len(a.live) != 1 || a.freed == 0 {
+			t.Fatalf("incremental %v: %d buffers in use, %d freed", incremental, len(a.live), a.freed)
+		}
+		if _, ok := a.live[&q.buf[0]]; !ok {
+			t.Fatal("deque buffer was not allocated by the allocator")
+		}
+	}
+}
+
+func TestAllocatorExternal(t *testing.T) {
+	var a countingAllocator[int]
+	buf := make([]int, 16)
+	q := NewFromBuffer(buf)
+	q.SetAllocator(&a)
+	for i := range 17 {
+		q.PushBack(i)
+	}
+	for range 17 {
+		q.PopBack()
+	}
+	// Only the buffer that replaced the provided buffer is freed.
+	if len(a.live) != 1 || a.freed != 1 || &q.buf[0] == &buf[0] {
+		t.Fatal("provided buffer was given to the allocator")
+	}
+
+	var s SmallDeque[int]
+	s.SetAllocator(&a)
+	for i := range 2 * smallSize {
+		s.PushBack(i)
+	}
+	if len(a.live) != 2 || a.freed != 1 {
+		t.Fatal("inline array was given to the allocator")
+	}
+}
+
+func TestPoolAllocator(t *testing.T) {
+	a := PoolAllocator[*int]()
+	if PoolAllocator[*int]() != a {
+		t.Fatal("expected same allocator for the same type")
+	}
+	if Allocator[int](PoolAllocator[int]()) == nil {
+		t.Fatal("expected allocator")
+	}
+
+	buf := a.Alloc(64)
+	if len(buf) != 64 {
+		t.Fatal("wrong buffer length", len(buf))
+	}
+	buf[5] = new(int)
+	a.Free(buf)
+	if buf[5] != nil {
+		t.Fatal("freed buffer was not cleared")
+	}
+	if len(a.Alloc(7)) != 7 {
+		t.Fatal("wrong buffer length for odd size")
+	}
+	a.Free(make([]*int, 7))
+
+	var q, r Deque[*int]
+	q.SetAllocator(a)
+	r.SetAllocator(a)
+	items := make([]*int, 300)
+	for i := range items {
+		items[i] = new(int)
+		*items[i] = i
+	}
+	for _, x := range items {
+		q.PushBack(x)
+		r.PushFront(x)
+	}
+	for range 200 {
+		q.PopFront()
+		r.PopFront()
+	}
+	if !slices.Equal(q.AppendToSlice(nil), items[200:]) {
+		t.Fatal("wrong contents")
+	}
+	for i := range r.Len() {
+		if *r.At(i) != 99-i {
+			t.Fatal("wrong item at", i)
+		}
+	}
+}
+
+// BenchmarkGrowShrink repeatedly fills and empties deques, so that each resize
+// discards a buffer.
+func BenchmarkGrowShrink(b *testing.B) {
+	run := func(b *testing.B, a Allocator[int]) {
+		b.ReportAllocs()
+		var q Deque[int]
+		q.SetAllocator(a)
+		for i := 0; i < b.N; i++ {
+			for j := range 4096 {
+				q.PushBack(j)
+			}
+			for q.Len() != 0 {
+				q.PopFront()
+			}
+		}
+	}
+	b.Run("Default", func(b *testing.B) { run(b, nil) })
+	b.Run("Pool", func(b *testing.B) { run(b, PoolAllocator[int]()) })
+}
@@ -0,0 +1,250 @@
+package deque
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// BlockingDeque is a deque that is safe for concurrent use, where removing an
+// item waits until an item is available, and adding an item waits until space
+// is available if the deque has a capacity limit. This supports
+// producer/consumer pipelines where producers push items onto one end and
+// consumers pop items from the other.
+//
+// The zero value for BlockingDeque is an empty deque with no capacity limit,
+// ready to use. A BlockingDeque must not be copied after first use.
+//
+// After [BlockingDeque.Close] is called, adding items fails with [ErrClosed].
+// Items remaining in a closed deque can still be removed, and once it is empty
+// removing items fails with ErrClosed instead of waiting.
+type BlockingDeque[T any] struct {
+	mu       sync.Mutex
+	d        Deque[T]
+	capacity int
+	closed   bool
+	// notEmpty is closed, and then replaced, to wake goroutines waiting for an
+	// item to become available.
+	notEmpty chan struct{}
+	// notFull is closed, and then replaced, to wake goroutines waiting for
+	// space to become available.
+	notFull chan struct{}
+}
+
+// NewBlockingDeque creates a new BlockingDeque that holds at most capacity
+// items. When the deque is full, adding an item waits until an item is
+// removed. A capacity of zero means the deque has no capacity limit, and adding
+// items never waits. If capacity is negative, NewBlockingDeque panics.
+func NewBlockingDeque[T any](capacity int) *BlockingDeque[T] {
+	if capacity < 0 {
+		panic(fmt.Errorf("%w: NewBlockingDeque(%d) called", ErrNegativeCount, capacity))
+	}
+	return &BlockingDeque[T]{
+		capacity: capacity,
+	}
+}
+
+// Capacity returns the maximum number of items the deque holds, or zero if the
+// deque has no capacity limit.
+func (q *BlockingDeque[T]) Capacity() int {
+	return q.capacity
+}
+
+// Len returns the number of items currently stored in the deque.
+func (q *BlockingDeque[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.d.Len()
+}
+
+// Close closes the deque and wakes all goroutines waiting to add or remove
+// items. Subsequent attempts to add items return [ErrClosed]. Items remaining
+// in the deque can still be removed, after which attempts to remove items
+// return ErrClosed. Calling Close more than once has no effect.
+func (q *BlockingDeque[T]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	broadcast(&q.notEmpty)
+	broadcast(&q.notFull)
+}
+
+// PushBack appends an item to the back of the deque, waiting for space to
+// become available if the deque is full. Returns [ErrClosed] if the deque is
+// closed.
+func (q *BlockingDeque[T]) PushBack(elem T) error {
+	return q.push(context.Background(), elem, false)
+}
+
+// PushFront prepends an item to the front of the deque, waiting for space to
+// become available if the deque is full. Returns [ErrClosed] if the deque is
+// closed.
+func (q *BlockingDeque[T]) PushFront(elem T) error {
+	return q.push(context.Background(), elem, true)
+}
+
+// PushBackContext appends an item to the back of the deque, waiting for space
+// to become available if the deque is full. If ctx is done before space is
+// available, the item is not added and the context's error is returned.
+// Returns [ErrClosed] if the deque is closed.
+func (q *BlockingDeque[T]) PushBackContext(ctx context.Context, elem T) error {
+	return q.push(ctx, elem, false)
+}
+
+// PushFrontContext prepends an item to the front of the deque, waiting for
+// space to become available if the deque is full. If ctx is done before space
+// is available, the item is not added and the context's error is returned.
+// Returns [ErrClosed] if the deque is closed.
+func (q *BlockingDeque[T]) PushFrontContext(ctx context.Context, elem T) error {
+	return q.push(ctx, elem, true)
+}
+
+// OfferBack appends an item to the back of the deque, waiting up to timeout
+// for space to become available if the deque is full. If no space becomes
+// available in time, the item is not added and [context.DeadlineExceeded] is
+// returned. A timeout of zero or less does not wait. Returns [ErrClosed] if the
+// deque is closed.
+func (q *BlockingDeque[T]) OfferBack(elem T, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return q.push(ctx, elem, false)
+}
+
+// OfferFront prepends an item to the front of the deque, waiting up to
+// timeout for space to become available if the deque is full. If no space
+// becomes available in time, the item is not added and
+// [context.DeadlineExceeded] is returned. A timeout of zero or less does not
+// wait. Returns [ErrClosed] if the deque is closed.
+func (q *BlockingDeque[T]) OfferFront(elem T, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return q.push(ctx, elem, true)
+}
+
+// PopFront removes and returns the item at the front of the deque, waiting
+// for an item to become available if the deque is empty. Returns [ErrClosed]
+// if the deque is closed and empty.
+func (q *BlockingDeque[T]) PopFront() (T, error) {
+	return q.pop(context.Background(), false)
+}
+
+// PopBack removes and returns the item at the back of the deque, waiting for
+// an item to become available if the deque is empty. Returns [ErrClosed] if the
+// deque is closed and empty.
+func (q *BlockingDeque[T]) PopBack() (T, error) {
+	return q.pop(context.Background(), true)
+}
+
+// PopFrontContext removes and returns the item at the front of the deque,
+// waiting for an item to become available if the deque is empty. If ctx is
+// done before an item is available, the context's error is returned. Returns
+// [ErrClosed] if the deque is closed and empty.
+func (q *BlockingDeque[T]) PopFrontContext(ctx context.Context) (T, error) {
+	return q.pop(ctx, false)
+}
+
+// PopBackContext removes and returns the item at the back of the deque,
+// waiting for an item to become available if the deque is empty. If ctx is
+// done before an item is available, the context's error is returned. Returns
+// [ErrClosed] if the deque is closed and empty.
+func (q *BlockingDeque[T]) PopBackContext(ctx context.Context) (T, error) {
+	return q.pop(ctx, true)
+}
+
+// PollFront removes and returns the item at the front of the deque, waiting
+// up to timeout for an item to become available if the deque is empty. If no
+// item becomes available in time, [context.DeadlineExceeded] is returned. A
+// timeout of zero or less does not wait. Returns [ErrClosed] if the deque is
+// closed and empty.
+func (q *BlockingDeque[T]) PollFront(timeout time.Duration) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return q.pop(ctx, false)
+}
+
+// PollBack removes and returns the item at the back of the deque, waiting up
+// to timeout for an item to become available if the deque is empty. If no item
+// becomes available in time, [context.DeadlineExceeded] is returned. A timeout
+// of zero or less does not wait. Returns [ErrClosed] if the deque is closed
+// and empty.
+func (q *BlockingDeque[T]) PollBack(timeout time.Duration) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return q.pop(ctx, true)
+}
+
+func (q *BlockingDeque[T]) push(ctx context.Context, elem T, front bool) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for !q.closed && q.capacity != 0 && q.d.Len() >= q.capacity {
+		if err := q.wait(ctx, &q.notFull); err != nil {
+			return err
+		}
+	}
+	if q.closed {
+		return ErrClosed
+	}
+
+	if front {
+		q.d.PushFront(elem)
+	} else {
+		q.d.PushBack(elem)
+	}
+	broadcast(&q.notEmpty)
+	return nil
+}
+
+func (q *BlockingDeque[T]) pop(ctx context.Context, back bool) (T, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for q.d.Len() == 0 {
+		if q.closed {
+			var zero T
+			return zero, ErrClosed
+		}
+		if err := q.wait(ctx, &q.notEmpty); err != nil {
+			var zero T
+			return zero, err
+		}
+	}
+
+	var elem T
+	if back {
+		elem = q.d.PopBack()
+	} else {
+		elem = q.d.PopFront()
+	}
+	if q.capacity != 0 {
+		broadcast(&q.notFull)
+	}
+	return elem, nil
+}
+
+// wait releases the lock and waits until the channel in ch is closed or ctx is
+// done, then re-acquires the lock. The lock must be held when calling wait.
+func (q *BlockingDeque[T]) wait(ctx context.Context, ch *chan struct{}) error {
+	if *ch == nil {
+		*ch = make(chan struct{})
+	}
+	c := *ch
+	q.mu.Unlock()
+	defer q.mu.Lock()
+
+	select {
+	case <-c:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// broadcast wakes all goroutines waiting on the channel in ch.
+func broadcast(ch *chan struct{}) {
+	if *ch != nil {
+		close(*ch)
+		*ch = nil
+	}
+}
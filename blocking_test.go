@@ -0,0 +1,247 @@
+package deque
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBlockingDequePopWaits(t *testing.T) {
+	var q BlockingDeque[int]
+
+	done := make(chan int)
+	go func() {
+		x, err := q.PopFront()
+		if err != nil {
+			t.Error("unexpected error:", err)
+		}
+		done <- x
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("PopFront returned before item was available")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	if err := q.PushBack(7); err != nil {
+		t.Fatal(err)
+	}
+	if x := <-done; x != 7 {
+		t.Fatal("expected 7, got", x)
+	}
+	if q.Len() != 0 {
+		t.Fatal("q.Len() =", q.Len(), "expected 0")
+	}
+}
+
+func TestBlockingDequeOrder(t *testing.T) {
+	var q BlockingDeque[int]
+	for i := range 3 {
+		if err := q.PushBack(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.PushFront(-1); err != nil {
+		t.Fatal(err)
+	}
+	if x, _ := q.PopBack(); x != 2 {
+		t.Fatal("expected 2 from back, got", x)
+	}
+	if x, _ := q.PopFront(); x != -1 {
+		t.Fatal("expected -1 from front, got", x)
+	}
+	if x, _ := q.PollBack(0); x != 1 {
+		t.Fatal("expected 1 from back, got", x)
+	}
+	if x, _ := q.PollFront(time.Second); x != 0 {
+		t.Fatal("expected 0 from front, got", x)
+	}
+}
+
+func TestBlockingDequeContext(t *testing.T) {
+	var q BlockingDeque[int]
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	_, err := q.PopFrontContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("expected context.Canceled, got", err)
+	}
+	_, err = q.PopBackContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatal("expected context.Canceled, got", err)
+	}
+
+	// An item that is available is returned even if ctx is done.
+	if err = q.PushFrontContext(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if x, err := q.PopBackContext(ctx); err != nil || x != 1 {
+		t.Fatal("expected 1, got", x, err)
+	}
+}
+
+func TestBlockingDequePollTimeout(t *testing.T) {
+	var q BlockingDeque[string]
+
+	_, err := q.PollFront(0)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("expected context.DeadlineExceeded, got", err)
+	}
+	start := time.Now()
+	_, err = q.PollBack(20 * time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("expected context.DeadlineExceeded, got", err)
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Fatal("PollBack returned before timeout")
+	}
+}
+
+func TestBlockingDequeCapacity(t *testing.T) {
+	q := NewBlockingDeque[int](2)
+	if q.Capacity() != 2 {
+		t.Fatal("wrong capacity")
+	}
+	if err := q.OfferBack(1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.OfferFront(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.OfferBack(2, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("expected context.DeadlineExceeded, got", err)
+	}
+	if err := q.OfferFront(2, 10*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("expected context.DeadlineExceeded, got", err)
+	}
+	if q.Len() != 2 {
+		t.Fatal("q.Len() =", q.Len(), "expected 2")
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- q.PushBack(2)
+	}()
+	select {
+	case <-done:
+		t.Fatal("PushBack returned while deque full")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if x, _ := q.PopFront(); x != 0 {
+		t.Fatal("expected 0, got", x)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if x, _ := q.PopBack(); x != 2 {
+		t.Fatal("expected 2, got", x)
+	}
+
+	assertPanics(t, "should panic with negative capacity", func() {
+		NewBlockingDeque[int](-1)
+	})
+}
+
+func TestBlockingDequeClose(t *testing.T) {
+	q := NewBlockingDeque[int](1)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 4)
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := q.PopFront()
+			errs <- err
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	q.Close()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if !errors.Is(err, ErrClosed) {
+			t.Fatal("expected ErrClosed, got", err)
+		}
+	}
+
+	if err := q.PushBack(1); !errors.Is(err, ErrClosed) {
+		t.Fatal("expected ErrClosed, got", err)
+	}
+	q.Close()
+
+	// Items remaining after close can be removed.
+	q = NewBlockingDeque[int](1)
+	if err := q.PushBack(1); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		done <- q.PushFront(2)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	q.Close()
+	if err := <-done; !errors.Is(err, ErrClosed) {
+		t.Fatal("expected ErrClosed, got", err)
+	}
+	if x, err := q.PopBack(); err != nil || x != 1 {
+		t.Fatal("expected 1, got", x, err)
+	}
+	if _, err := q.PollFront(time.Second); !errors.Is(err, ErrClosed) {
+		t.Fatal("expected ErrClosed, got", err)
+	}
+}
+
+func TestBlockingDequeProducerConsumer(t *testing.T) {
+	const (
+		producers = 4
+		perG      = 500
+	)
+	q := NewBlockingDeque[int](8)
+
+	var wg sync.WaitGroup
+	for range producers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perG {
+				if err := q.PushBack(i); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	results := make(chan int)
+	for range 2 {
+		go func() {
+			var n int
+			for {
+				_, err := q.PopFront()
+				if err != nil {
+					results <- n
+					return
+				}
+				n++
+			}
+		}()
+	}
+
+	wg.Wait()
+	for q.Len() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	q.Close()
+	total := <-results + <-results
+	if total != producers*perG {
+		t.Fatal("consumed", total, "items, expected", producers*perG)
+	}
+}
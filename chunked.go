@@ -0,0 +1,449 @@
+package deque
+
+import (
+	"fmt"
+	"iter"
+)
+
+// chunkShift sets the number of items in each block of a ChunkedDeque, which
+// is 1<<chunkShift.
+const (
+	chunkShift = 9
+	chunkSize  = 1 << chunkShift
+	chunkMask  = chunkSize - 1
+)
+
+// ChunkedDeque is a deque that stores its items in fixed-size blocks, instead
+// of in a single ring buffer. The blocks are located using a ring-buffered
+// index of blocks, which is a [Deque] of block pointers.
+//
+// This suits very large queues. Adding an item never moves existing items,
+// since a new block is added when the blocks at either end are full, and only
+// the index of blocks is resized. Memory use is proportional to the number of
+// items, plus the unused parts of the blocks at either end, instead of up to
+// twice the number of items. A block is released as soon as it is emptied,
+// except that one emptied block is kept for reuse, to avoid repeated
+// allocation when the number of items goes back and forth across a block
+// boundary.
+//
+// Access to items is slower than with Deque, since each access must first
+// locate the block that holds the item. For queues that are not very large,
+// use Deque.
+//
+// The zero value for ChunkedDeque is an empty deque ready to use.
+type ChunkedDeque[T any] struct {
+	chunks Deque[*[chunkSize]T]
+	// head is the position of the front item in the first block.
+	head  int
+	count int
+	// spare is an emptied block that is kept for reuse.
+	spare *[chunkSize]T
+	// ver is incremented by every change to the items or to their positions,
+	// so that iterators can detect modification.
+	ver uint
+}
+
+// Len returns the number of elements currently stored in the queue. If q is
+// nil, q.Len() returns zero.
+func (q *ChunkedDeque[T]) Len() int {
+	if q == nil {
+		return 0
+	}
+	return q.count
+}
+
+// PushBack appends an element to the back of the queue.
+func (q *ChunkedDeque[T]) PushBack(elem T) {
+	end := q.head + q.count
+	if end == q.chunks.Len()<<chunkShift {
+		q.chunks.PushBack(q.newChunk())
+	}
+	q.chunks.At(end >> chunkShift)[end&chunkMask] = elem
+	q.count++
+	q.ver++
+}
+
+// PushFront prepends an element to the front of the queue.
+func (q *ChunkedDeque[T]) PushFront(elem T) {
+	if q.head == 0 {
+		q.chunks.PushFront(q.newChunk())
+		q.head = chunkSize
+	}
+	q.head--
+	q.chunks.Front()[q.head] = elem
+	q.count++
+	q.ver++
+}
+
+// PopFront removes and returns the element from the front of the queue. If the
+// queue is empty, the call panics with an error wrapping [ErrEmpty].
+func (q *ChunkedDeque[T]) PopFront() T {
+	if q.count <= 0 {
+		panic(fmt.Errorf("%w: PopFront() called", ErrEmpty))
+	}
+	return q.popFront()
+}
+
+// TryPopFront removes and returns the element from the front of the queue. If
+// the queue is empty, ok is false and the zero value is returned.
+func (q *ChunkedDeque[T]) TryPopFront() (elem T, ok bool) {
+	if q.Len() == 0 {
+		return elem, false
+	}
+	return q.popFront(), true
+}
+
+// PopBack removes and returns the element from the back of the queue. If the
+// queue is empty, the call panics with an error wrapping [ErrEmpty].
+func (q *ChunkedDeque[T]) PopBack() T {
+	if q.count <= 0 {
+		panic(fmt.Errorf("%w: PopBack() called", ErrEmpty))
+	}
+	return q.popBack()
+}
+
+// TryPopBack removes and returns the element from the back of the queue. If
+// the queue is empty, ok is false and the zero value is returned.
+func (q *ChunkedDeque[T]) TryPopBack() (elem T, ok bool) {
+	if q.Len() == 0 {
+		return elem, false
+	}
+	return q.popBack(), true
+}
+
+// Front returns the element at the front of the queue. This call panics if the
+// queue is empty.
+func (q *ChunkedDeque[T]) Front() T {
+	if q.count <= 0 {
+		panic(fmt.Errorf("%w: Front() called", ErrEmpty))
+	}
+	return q.chunks.Front()[q.head]
+}
+
+// TryFront returns the element at the front of the queue. If the queue is
+// empty, ok is false and the zero value is returned.
+func (q *ChunkedDeque[T]) TryFront() (elem T, ok bool) {
+	if q.Len() == 0 {
+		return elem, false
+	}
+	return q.chunks.Front()[q.head], true
+}
+
+// Back returns the element at the back of the queue. This call panics if the
+// queue is empty.
+func (q *ChunkedDeque[T]) Back() T {
+	if q.count <= 0 {
+		panic(fmt.Errorf("%w: Back() called", ErrEmpty))
+	}
+	return *q.item(q.count - 1)
+}
+
+// TryBack returns the element at the back of the queue. If the queue is empty,
+// ok is false and the zero value is returned.
+func (q *ChunkedDeque[T]) TryBack() (elem T, ok bool) {
+	if q.Len() == 0 {
+		return elem, false
+	}
+	return *q.item(q.count - 1), true
+}
+
+// At returns the element at index i in the queue without removing the element
+// from the queue. At(0) refers to the first element and is the same as
+// [ChunkedDeque.Front]. If the index is invalid, the call panics with an
+// [*IndexError].
+func (q *ChunkedDeque[T]) At(i int) T {
+	q.checkRange(i)
+	return *q.item(i)
+}
+
+// TryAt returns the element at index i in the queue without removing the
+// element from the queue. If the index is invalid, ok is false and the zero
+// value is returned.
+func (q *ChunkedDeque[T]) TryAt(i int) (elem T, ok bool) {
+	if i < 0 || i >= q.Len() {
+		return elem, false
+	}
+	return *q.item(i), true
+}
+
+// Set assigns the item to index i in the queue. If the index is invalid, the
+// call panics with an [*IndexError].
+func (q *ChunkedDeque[T]) Set(i int, item T) {
+	q.checkRange(i)
+	*q.item(i) = item
+	q.ver++
+}
+
+// TrySet assigns the item to index i in the queue. If the index is invalid,
+// the queue is not modified and false is returned.
+func (q *ChunkedDeque[T]) TrySet(i int, item T) bool {
+	if i < 0 || i >= q.Len() {
+		return false
+	}
+	*q.item(i) = item
+	q.ver++
+	return true
+}
+
+// Swap exchanges the two values at idxA and idxB. It panics if either index is
+// out of range.
+func (q *ChunkedDeque[T]) Swap(idxA, idxB int) {
+	q.checkRange(idxA)
+	q.checkRange(idxB)
+	a, b := q.item(idxA), q.item(idxB)
+	*a, *b = *b, *a
+	q.ver++
+}
+
+// Iter returns a go iterator to range over all items in the queue, yielding
+// each item from front (index 0) to back (index Len()-1). Modification of the
+// queue during iteration panics with [ErrModifiedDuringIteration].
+func (q *ChunkedDeque[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		ver := q.ver
+		for i := range q.Len() {
+			if q.ver != ver {
+				panic(ErrModifiedDuringIteration)
+			}
+			if !yield(*q.item(i)) {
+				return
+			}
+		}
+	}
+}
+
+// RIter returns a reverse go iterator to range over all items in the queue,
+// yielding each item from back (index Len()-1) to front (index 0).
+// Modification of the queue during iteration panics with
+// [ErrModifiedDuringIteration].
+func (q *ChunkedDeque[T]) RIter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		ver := q.ver
+		for i := q.Len() - 1; i >= 0; i-- {
+			if q.ver != ver {
+				panic(ErrModifiedDuringIteration)
+			}
+			if !yield(*q.item(i)) {
+				return
+			}
+		}
+	}
+}
+
+// Index returns the index into the queue of the first item satisfying
+// f(item), or -1 if none do. Search is linear starting with index 0.
+func (q *ChunkedDeque[T]) Index(f func(T) bool) int {
+	for i := range q.Len() {
+		if f(*q.item(i)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// RIndex is the same as Index, but searches from Back to Front. The index
+// returned is from Front to Back, where index 0 is the index of the item
+// returned by [ChunkedDeque.Front].
+func (q *ChunkedDeque[T]) RIndex(f func(T) bool) int {
+	for i := q.Len() - 1; i >= 0; i-- {
+		if f(*q.item(i)) {
+			return i
+		}
+	}
+	return -1
+}
+
+// AppendToSlice appends the items in the queue, from front to back, to the
+// given slice, and returns the resulting slice.
+func (q *ChunkedDeque[T]) AppendToSlice(out []T) []T {
+	if q.Len() == 0 {
+		return out
+	}
+	start, end := q.head, q.head+q.count
+	for c := range q.chunks.Len() {
+		n := min(end-c<<chunkShift, chunkSize)
+		out = append(out, q.chunks.At(c)[start:n]...)
+		start = 0
+	}
+	return out
+}
+
+// Insert is used to insert an element into the middle of the queue, before the
+// element at the specified index. Insert(0,e) is the same as PushFront(e) and
+// Insert(Len(),e) is the same as PushBack(e). Out of range indexes result in
+// pushing the item onto the front or back of the queue.
+//
+// Complexity of this function is constant plus linear in the lesser of the
+// distances between the index and either of the ends of the queue. Items on
+// that side are shifted with block copies.
+func (q *ChunkedDeque[T]) Insert(at int, item T) {
+	if at <= 0 {
+		q.PushFront(item)
+		return
+	}
+	if at >= q.Len() {
+		q.PushBack(item)
+		return
+	}
+	if at*2 < q.count {
+		// Move the items before the insertion point toward the front.
+		q.PushFront(item)
+		q.moveItems(q.head, q.head+1, at)
+	} else {
+		// Move the items after the insertion point toward the back.
+		q.PushBack(item)
+		q.moveItems(q.head+at+1, q.head+at, q.count-1-at)
+	}
+	*q.item(at) = item
+}
+
+// Remove removes and returns an element from the middle of the queue, at the
+// specified index. Remove(0) is the same as [ChunkedDeque.PopFront] and
+// Remove(Len()-1) is the same as [ChunkedDeque.PopBack]. If the index is
+// invalid, the call panics with an [*IndexError].
+//
+// Complexity of this function is constant plus linear in the lesser of the
+// distances between the index and either of the ends of the queue. Items on
+// that side are shifted with block copies.
+func (q *ChunkedDeque[T]) Remove(at int) T {
+	q.checkRange(at)
+	return q.remove(at)
+}
+
+// TryRemove removes and returns an element from the middle of the queue, at
+// the specified index. If the index is invalid, ok is false and the zero value
+// is returned.
+func (q *ChunkedDeque[T]) TryRemove(at int) (elem T, ok bool) {
+	if at < 0 || at >= q.Len() {
+		return elem, false
+	}
+	return q.remove(at), true
+}
+
+// Clear removes all elements from the queue, and releases all blocks except
+// one, which is kept for reuse.
+func (q *ChunkedDeque[T]) Clear() {
+	if q.spare == nil && q.chunks.Len() != 0 {
+		q.spare = q.chunks.Front()
+		clear(q.spare[:])
+	}
+	q.chunks.Clear()
+	q.head = 0
+	q.count = 0
+	q.ver++
+}
+
+func (q *ChunkedDeque[T]) checkRange(i int) {
+	if i < 0 || i >= q.count {
+		panic(&IndexError{Index: i, Len: q.Len()})
+	}
+}
+
+// item returns a pointer to the item at index i, which must be in range.
+func (q *ChunkedDeque[T]) item(i int) *T {
+	p := q.head + i
+	return &q.chunks.At(p >> chunkShift)[p&chunkMask]
+}
+
+// popFront removes and returns the front element without checking for an
+// empty queue. The first block is released once it is empty.
+func (q *ChunkedDeque[T]) popFront() T {
+	chunk := q.chunks.Front()
+	ret := chunk[q.head]
+	var zero T
+	chunk[q.head] = zero
+	q.head++
+	q.count--
+	q.ver++
+	if q.head == chunkSize || q.count == 0 {
+		q.releaseChunk(q.chunks.PopFront())
+		q.head = 0
+	}
+	return ret
+}
+
+// popBack removes and returns the back element without checking for an empty
+// queue. The last block is released once it is empty.
+func (q *ChunkedDeque[T]) popBack() T {
+	q.count--
+	q.ver++
+	p := q.head + q.count
+	chunk := q.chunks.Back()
+	ret := chunk[p&chunkMask]
+	var zero T
+	chunk[p&chunkMask] = zero
+	if p&chunkMask == 0 || q.count == 0 {
+		q.releaseChunk(q.chunks.PopBack())
+		if q.count == 0 {
+			q.head = 0
+		}
+	}
+	return ret
+}
+
+// remove removes and returns the element at index at, which must be in range.
+func (q *ChunkedDeque[T]) remove(at int) T {
+	ret := *q.item(at)
+	if at*2 < q.count {
+		// Move the items before the removed item toward the back.
+		q.moveItems(q.head+1, q.head, at)
+		q.popFront()
+	} else {
+		// Move the items after the removed item toward the front.
+		q.moveItems(q.head+at, q.head+at+1, q.count-at-1)
+		q.popBack()
+	}
+	return ret
+}
+
+// moveItems moves n items from position src to position dst, where positions
+// count items from the start of the first block. The ranges may overlap.
+// Items are moved with one block copy for each part of the ranges that is
+// within a single block.
+func (q *ChunkedDeque[T]) moveItems(dst, src, n int) {
+	if dst < src {
+		// Copy from the start of the range.
+		for n != 0 {
+			so, do := src&chunkMask, dst&chunkMask
+			k := min(n, chunkSize-so, chunkSize-do)
+			s := q.chunks.At(src >> chunkShift)
+			d := q.chunks.At(dst >> chunkShift)
+			copy(d[do:do+k], s[so:so+k])
+			src += k
+			dst += k
+			n -= k
+		}
+		return
+	}
+	// Copy from the end of the range.
+	srcEnd, dstEnd := src+n, dst+n
+	for n != 0 {
+		// Offsets, within their blocks, just past the last items to move.
+		so := (srcEnd-1)&chunkMask + 1
+		do := (dstEnd-1)&chunkMask + 1
+		k := min(n, so, do)
+		s := q.chunks.At((srcEnd - 1) >> chunkShift)
+		d := q.chunks.At((dstEnd - 1) >> chunkShift)
+		copy(d[do-k:do], s[so-k:so])
+		srcEnd -= k
+		dstEnd -= k
+		n -= k
+	}
+}
+
+// newChunk returns an empty block, reusing the spare block if there is one.
+func (q *ChunkedDeque[T]) newChunk() *[chunkSize]T {
+	if chunk := q.spare; chunk != nil {
+		q.spare = nil
+		return chunk
+	}
+	return new([chunkSize]T)
+}
+
+// releaseChunk keeps an emptied block for reuse, if there is not already a
+// spare block, so that the block can be garbage collected otherwise.
+func (q *ChunkedDeque[T]) releaseChunk(chunk *[chunkSize]T) {
+	if q.spare == nil {
+		q.spare = chunk
+	}
+}
@@ -0,0 +1,279 @@
+package deque
+
+import (
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestChunkedDequeEmpty(t *testing.T) {
+	var q ChunkedDeque[int]
+	if q.Len() != 0 {
+		t.Fatal("expected empty deque")
+	}
+	var nilDeque *ChunkedDeque[int]
+	if nilDeque.Len() != 0 {
+		t.Fatal("expected zero length for nil deque")
+	}
+	if _, ok := q.TryPopFront(); ok {
+		t.Fatal("expected TryPopFront to fail")
+	}
+	if _, ok := q.TryPopBack(); ok {
+		t.Fatal("expected TryPopBack to fail")
+	}
+	if _, ok := q.TryFront(); ok {
+		t.Fatal("expected TryFront to fail")
+	}
+	if _, ok := q.TryBack(); ok {
+		t.Fatal("expected TryBack to fail")
+	}
+	if _, ok := q.TryAt(0); ok {
+		t.Fatal("expected TryAt to fail")
+	}
+	if q.TrySet(0, 1) {
+		t.Fatal("expected TrySet to fail")
+	}
+	if _, ok := q.TryRemove(0); ok {
+		t.Fatal("expected TryRemove to fail")
+	}
+	if err := recoverError(func() { q.PopFront() }); !errors.Is(err, ErrEmpty) {
+		t.Fatal("expected ErrEmpty, got", err)
+	}
+	assertPanics(t, "PopBack", func() { q.PopBack() })
+	assertPanics(t, "Front", func() { q.Front() })
+	assertPanics(t, "Back", func() { q.Back() })
+	var idxErr *IndexError
+	if err := recoverError(func() { q.At(0) }); !errors.As(err, &idxErr) {
+		t.Fatal("expected *IndexError, got", err)
+	}
+	assertPanics(t, "Set", func() { q.Set(0, 1) })
+	assertPanics(t, "Remove", func() { q.Remove(0) })
+}
+
+func TestChunkedDequeBlocks(t *testing.T) {
+	var q ChunkedDeque[int]
+	for i := range chunkSize {
+		q.PushBack(i)
+	}
+	if q.chunks.Len() != 1 {
+		t.Fatal("expected 1 block, got", q.chunks.Len())
+	}
+	q.PushBack(chunkSize)
+	q.PushFront(-1)
+	if q.chunks.Len() != 3 {
+		t.Fatal("expected 3 blocks, got", q.chunks.Len())
+	}
+	first := q.chunks.Front()
+
+	// Adding blocks does not move existing items.
+	for i := range 10 * chunkSize {
+		q.PushBack(i)
+	}
+	if q.chunks.Front() != first || q.Front() != -1 || q.At(1) != 0 {
+		t.Fatal("items moved when blocks were added")
+	}
+
+	// Blocks are released as they empty, and one is kept for reuse.
+	if q.PopFront() != -1 {
+		t.Fatal("wrong value popped")
+	}
+	if q.chunks.Len() != 12 || q.spare != first {
+		t.Fatal("expected emptied block to be kept as spare")
+	}
+	for range chunkSize {
+		q.PopFront()
+	}
+	if q.chunks.Len() != 11 {
+		t.Fatal("expected 11 blocks, got", q.chunks.Len())
+	}
+	for _, x := range first {
+		if x != 0 {
+			t.Fatal("spare block holds removed items")
+		}
+	}
+	for q.Len() != 0 {
+		q.PopBack()
+	}
+	if q.chunks.Len() != 0 || q.head != 0 {
+		t.Fatal("expected no blocks in empty deque")
+	}
+
+	q.PushFront(1)
+	q.PushBack(2)
+	if q.spare != nil || q.Front() != 1 || q.Back() != 2 {
+		t.Fatal("expected spare block to be reused")
+	}
+	q.Clear()
+	if q.Len() != 0 || q.chunks.Len() != 0 || q.spare == nil {
+		t.Fatal("expected empty deque with spare block after Clear")
+	}
+}
+
+func TestChunkedDequeRandom(t *testing.T) {
+	rng := rand.New(rand.NewPCG(9, 10))
+	var q ChunkedDeque[int]
+	var model []int
+	var next int
+	for round := range 60000 {
+		// Favor pushes in growing phases and pops in shrinking phases, so that
+		// blocks are added and released at both ends.
+		push := rng.IntN(10) < 7
+		if (round/10000)%2 == 1 {
+			push = !push
+		}
+		switch op := rng.IntN(20); {
+		case op < 10 && push:
+			next++
+			if op%2 == 0 {
+				q.PushBack(next)
+				model = append(model, next)
+			} else {
+				q.PushFront(next)
+				model = slices.Insert(model, 0, next)
+			}
+		case op < 10:
+			if len(model) == 0 {
+				continue
+			}
+			if op%2 == 0 {
+				if q.PopBack() != model[len(model)-1] {
+					t.Fatalf("round %d: wrong value from PopBack", round)
+				}
+				model = model[:len(model)-1]
+			} else {
+				if q.PopFront() != model[0] {
+					t.Fatalf("round %d: wrong value from PopFront", round)
+				}
+				model = model[1:]
+			}
+		case op < 17:
+			if len(model) == 0 {
+				continue
+			}
+			i := rng.IntN(len(model))
+			if q.At(i) != model[i] {
+				t.Fatalf("round %d: wrong value at %d", round, i)
+			}
+			next++
+			q.Set(i, next)
+			model[i] = next
+		case op == 17:
+			i := rng.IntN(len(model) + 1)
+			next++
+			q.Insert(i, next)
+			model = slices.Insert(model, i, next)
+		case op == 18:
+			if len(model) == 0 {
+				continue
+			}
+			i := rng.IntN(len(model))
+			if q.Remove(i) != model[i] {
+				t.Fatalf("round %d: wrong value removed from %d", round, i)
+			}
+			model = slices.Delete(model, i, i+1)
+		default:
+			if !slices.Equal(q.AppendToSlice(nil), model) {
+				t.Fatalf("round %d: wrong contents", round)
+			}
+		}
+		if q.Len() != len(model) {
+			t.Fatalf("round %d: length %d, expected %d", round, q.Len(), len(model))
+		}
+	}
+}
+
+func TestChunkedDequeInsertRemove(t *testing.T) {
+	rng := rand.New(rand.NewPCG(11, 12))
+	var q ChunkedDeque[int]
+	var model []int
+	// Move items across several blocks in both directions.
+	for i := range 5 * chunkSize {
+		at := rng.IntN(len(model) + 1)
+		q.Insert(at, i)
+		model = slices.Insert(model, at, i)
+	}
+	if !slices.Equal(q.AppendToSlice(nil), model) {
+		t.Fatal("wrong contents after inserts")
+	}
+	for len(model) != 0 {
+		at := rng.IntN(len(model))
+		if x, ok := q.TryRemove(at); !ok || x != model[at] {
+			t.Fatalf("wrong item removed from index %d", at)
+		}
+		model = slices.Delete(model, at, at+1)
+	}
+	if q.Len() != 0 || q.chunks.Len() != 0 {
+		t.Fatal("expected empty deque")
+	}
+}
+
+func TestChunkedDequeIter(t *testing.T) {
+	var q ChunkedDeque[int]
+	for i := range 3 * chunkSize {
+		q.PushBack(i)
+	}
+	var i int
+	for x := range q.Iter() {
+		if x != i {
+			t.Fatal("wrong value from Iter:", x)
+		}
+		i++
+	}
+	if i != q.Len() {
+		t.Fatal("Iter did not yield all items")
+	}
+	for x := range q.RIter() {
+		i--
+		if x != i {
+			t.Fatal("wrong value from RIter:", x)
+		}
+	}
+	if q.Index(func(x int) bool { return x > chunkSize }) != chunkSize+1 {
+		t.Fatal("wrong index")
+	}
+	if q.RIndex(func(x int) bool { return x < chunkSize }) != chunkSize-1 {
+		t.Fatal("wrong reverse index")
+	}
+	if q.Index(func(x int) bool { return x < 0 }) != -1 {
+		t.Fatal("expected -1 from Index")
+	}
+	q.Swap(0, q.Len()-1)
+	if q.Front() != 3*chunkSize-1 || q.Back() != 0 {
+		t.Fatal("wrong values after Swap")
+	}
+
+	err := recoverError(func() {
+		for range q.Iter() {
+			q.PushBack(1)
+		}
+	})
+	if !errors.Is(err, ErrModifiedDuringIteration) {
+		t.Fatal("expected ErrModifiedDuringIteration, got", err)
+	}
+	err = recoverError(func() {
+		for range q.RIter() {
+			q.PopFront()
+		}
+	})
+	if !errors.Is(err, ErrModifiedDuringIteration) {
+		t.Fatal("expected ErrModifiedDuringIteration, got", err)
+	}
+}
+
+func BenchmarkChunkedPushBack(b *testing.B) {
+	var q ChunkedDeque[int]
+	for i := 0; i < b.N; i++ {
+		q.PushBack(i)
+	}
+}
+
+func BenchmarkChunkedSerial(b *testing.B) {
+	var q ChunkedDeque[int]
+	for i := 0; i < b.N; i++ {
+		q.PushBack(i)
+	}
+	for i := 0; i < b.N; i++ {
+		q.PopFront()
+	}
+}
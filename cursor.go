@@ -0,0 +1,197 @@
+package deque
+
+import "fmt"
+
+// Cursor is a position in a Deque that can move in either direction, and
+// through which items can be read, replaced, removed, and inserted while
+// walking the Deque. Unlike an iterator, a Cursor remains valid when the Deque
+// is modified through the Cursor.
+//
+// A Cursor is either on an item, which is its current item, or in the gap
+// between two items, or at either end of the Deque. [Cursor.Next] and
+// [Cursor.Prev] move the Cursor to the next or previous item:
+//
+//	c := q.Cursor()
+//	for c.Next() {
+//		if expired(c.Value()) {
+//			c.Remove()
+//		}
+//	}
+//
+// Modifying the Deque other than through the Cursor invalidates the Cursor,
+// after which any use of the Cursor panics with [ErrModifiedDuringIteration].
+// Inserting and removing items are done as with [Deque.Insert] and
+// [Deque.Remove], so their cost is linear in the lesser of the distances to
+// the ends of the Deque.
+type Cursor[T any] struct {
+	q *Deque[T]
+	// i is the index of the current item if ok is true. Otherwise, the cursor
+	// is in the gap before the item at index i, which may be q.Len().
+	i   int
+	ok  bool
+	ver uint
+}
+
+// Cursor returns a Cursor positioned before the front of the Deque, so that
+// the first call to [Cursor.Next] moves to the front item.
+func (q *Deque[T]) Cursor() Cursor[T] {
+	return Cursor[T]{q: q, ver: q.ver}
+}
+
+// RCursor returns a Cursor positioned after the back of the Deque, so that the
+// first call to [Cursor.Prev] moves to the back item.
+func (q *Deque[T]) RCursor() Cursor[T] {
+	return Cursor[T]{q: q, i: q.Len(), ver: q.ver}
+}
+
+// CursorAt returns a Cursor positioned on the item at index i. If the index is
+// invalid, the call panics with an [*IndexError].
+func (q *Deque[T]) CursorAt(i int) Cursor[T] {
+	q.checkRange(i)
+	return Cursor[T]{q: q, i: i, ok: true, ver: q.ver}
+}
+
+// Next moves the Cursor to the next item, toward the back of the Deque, and
+// reports whether there is one. If there is no next item, the Cursor is left
+// after the back of the Deque.
+func (c *Cursor[T]) Next() bool {
+	c.check()
+	if c.ok {
+		c.i++
+	}
+	c.ok = c.i < c.q.count
+	return c.ok
+}
+
+// Prev moves the Cursor to the previous item, toward the front of the Deque,
+// and reports whether there is one. If there is no previous item, the Cursor
+// is left before the front of the Deque.
+func (c *Cursor[T]) Prev() bool {
+	c.check()
+	if c.i == 0 {
+		c.ok = false
+		return false
+	}
+	c.i--
+	c.ok = true
+	return true
+}
+
+// Index returns the index of the current item, or -1 if the Cursor is not on
+// an item.
+func (c *Cursor[T]) Index() int {
+	c.check()
+	if !c.ok {
+		return -1
+	}
+	return c.i
+}
+
+// Value returns the current item. If the Cursor is not on an item, the call
+// panics with an error wrapping [ErrOutOfRange].
+func (c *Cursor[T]) Value() T {
+	c.checkItem("Value")
+	return c.q.At(c.i)
+}
+
+// Set replaces the current item. If the Cursor is not on an item, the call
+// panics with an error wrapping [ErrOutOfRange].
+func (c *Cursor[T]) Set(item T) {
+	c.checkItem("Set")
+	c.q.Set(c.i, item)
+	c.ver = c.q.ver
+}
+
+// Remove removes and returns the current item. The Cursor is left in the gap
+// where the item was, so that [Cursor.Next] moves to the item that followed
+// the removed item, and [Cursor.Prev] moves to the item that preceded it. If
+// the Cursor is not on an item, the call panics with an error wrapping
+// [ErrOutOfRange].
+func (c *Cursor[T]) Remove() T {
+	c.checkItem("Remove")
+	item := c.q.Remove(c.i)
+	c.ok = false
+	c.ver = c.q.ver
+	return item
+}
+
+// InsertBefore inserts an item before the current item, or into the gap where
+// the Cursor is. The Cursor stays on the current item, or in the gap after
+// the inserted item, so that [Cursor.Prev] moves to the inserted item.
+//
+// If the Deque has a maximum length, the overflow policy is applied as with
+// [Deque.Insert]. If the current item is evicted, the Cursor is left in the
+// gap where the item was.
+func (c *Cursor[T]) InsertBefore(item T) {
+	c.check()
+	c.insert(c.i, item, true)
+}
+
+// InsertAfter inserts an item after the current item, or into the gap where
+// the Cursor is. The Cursor stays on the current item, or in the gap before
+// the inserted item, so that [Cursor.Next] moves to the inserted item.
+//
+// If the Deque has a maximum length, the overflow policy is applied as with
+// [Deque.Insert]. If the current item is evicted, the Cursor is left in the
+// gap where the item was.
+func (c *Cursor[T]) InsertAfter(item T) {
+	c.check()
+	if c.ok {
+		c.insert(c.i+1, item, false)
+	} else {
+		c.insert(c.i, item, false)
+	}
+}
+
+// insert inserts item at index at, and moves the cursor to keep its position.
+// If before is true, the item is inserted before the cursor position,
+// otherwise after it.
+func (c *Cursor[T]) insert(at int, item T, before bool) {
+	q := c.q
+	n := q.count
+	q.Insert(at, item)
+	if q.ver == c.ver {
+		// The item was rejected.
+		return
+	}
+	c.ver = q.ver
+	switch {
+	case q.count > n:
+		if before {
+			c.i++
+		}
+	case at <= 0:
+		// The item was pushed onto the front, and the back item evicted.
+		if before {
+			c.i++
+		}
+		if c.i >= q.count {
+			c.i = q.count
+			c.ok = false
+		}
+	default:
+		// The front item was evicted, so the items before at moved forward.
+		if !before {
+			c.i--
+		}
+		if c.i < 0 {
+			c.i = 0
+			c.ok = false
+		}
+	}
+}
+
+// check panics if the Deque was modified other than through the cursor.
+func (c *Cursor[T]) check() {
+	if c.ver != c.q.ver {
+		panic(ErrModifiedDuringIteration)
+	}
+}
+
+// checkItem panics if the cursor is not valid or is not on an item.
+func (c *Cursor[T]) checkItem(method string) {
+	c.check()
+	if !c.ok {
+		panic(fmt.Errorf("%w: Cursor.%s() called with no current item", ErrOutOfRange, method))
+	}
+}
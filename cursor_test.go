@@ -0,0 +1,257 @@
+package deque
+
+import (
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestCursor(t *testing.T) {
+	var q Deque[int]
+	for i := range 20 {
+		q.PushBack(i)
+	}
+
+	// Remove odd items and insert a negative copy before each even one.
+	c := q.Cursor()
+	if c.Index() != -1 {
+		t.Fatal("expected no current item")
+	}
+	for c.Next() {
+		x := c.Value()
+		if x%2 == 1 {
+			if c.Remove() != x {
+				t.Fatal("wrong item removed")
+			}
+			continue
+		}
+		c.InsertBefore(-x)
+		c.Set(x * 10)
+	}
+	want := []int{0, 0, -2, 20, -4, 40, -6, 60, -8, 80, -10, 100, -12, 120, -14, 140, -16, 160, -18, 180}
+	if !slices.Equal(q.AppendToSlice(nil), want) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+	if c.Next() || c.Index() != -1 {
+		t.Fatal("expected cursor after back")
+	}
+
+	// Walk back from the back, inserting after each item.
+	c = q.RCursor()
+	n := 0
+	for c.Prev() {
+		if c.Index() != len(want)-1-n {
+			t.Fatal("wrong index", c.Index())
+		}
+		c.InsertAfter(1)
+		n++
+	}
+	if n != len(want) || q.Len() != 2*len(want) {
+		t.Fatal("wrong number of items visited")
+	}
+	if c.Prev() {
+		t.Fatal("expected cursor before front")
+	}
+	// Inserting after the gap before the front inserts the new front item.
+	c.InsertAfter(-100)
+	if !c.Next() || c.Value() != -100 || q.Front() != -100 {
+		t.Fatal("expected Next to move to inserted item")
+	}
+
+	c = q.CursorAt(5)
+	if c.Value() != -2 {
+		t.Fatal("wrong item at cursor")
+	}
+	c.Remove()
+	if !c.Prev() || c.Value() != 1 || !c.Next() || c.Value() != 1 {
+		t.Fatal("wrong items around removed item")
+	}
+	if err := recoverError(func() { q.CursorAt(q.Len()) }); !errors.As(err, new(*IndexError)) {
+		t.Fatal("expected *IndexError, got", err)
+	}
+
+	c.Remove()
+	if err := recoverError(func() { c.Value() }); !errors.Is(err, ErrOutOfRange) {
+		t.Fatal("expected ErrOutOfRange, got", err)
+	}
+	assertPanics(t, "Set with no current item", func() { c.Set(1) })
+	assertPanics(t, "Remove with no current item", func() { c.Remove() })
+
+	q.Set(0, 5)
+	for name, f := range map[string]func(){
+		"Next":         func() { c.Next() },
+		"Prev":         func() { c.Prev() },
+		"Index":        func() { c.Index() },
+		"Value":        func() { c.Value() },
+		"InsertBefore": func() { c.InsertBefore(1) },
+	} {
+		if err := recoverError(f); !errors.Is(err, ErrModifiedDuringIteration) {
+			t.Errorf("%s: expected ErrModifiedDuringIteration, got %v", name, err)
+		}
+	}
+
+	// RetainMut may change items without removing any.
+	c = q.Cursor()
+	q.RetainMut(func(*int) bool { return true })
+	if err := recoverError(func() { c.Next() }); !errors.Is(err, ErrModifiedDuringIteration) {
+		t.Fatal("expected ErrModifiedDuringIteration after RetainMut, got", err)
+	}
+
+	var empty Deque[int]
+	c = empty.Cursor()
+	if c.Next() || c.Prev() {
+		t.Fatal("expected no items")
+	}
+	c.InsertBefore(1)
+	c.InsertAfter(2)
+	if !slices.Equal(empty.AppendToSlice(nil), []int{1, 2}) {
+		t.Fatal("wrong contents:", empty.AppendToSlice(nil))
+	}
+}
+
+func TestCursorRandom(t *testing.T) {
+	for _, maxLen := range []int{0, 1, 5, 40} {
+		cursorRandom(t, maxLen)
+	}
+}
+
+// cursorRandom applies random cursor operations to a Deque and to a slice
+// model. Each item is unique, and the model cursor is either on an item, or in
+// the gap before an item or after the back.
+func cursorRandom(t *testing.T, maxLen int) {
+	rng := rand.New(rand.NewPCG(15, uint64(maxLen)))
+	var q Deque[int]
+	q.SetMaxLen(maxLen, OverflowDrop)
+	var model []int
+	var next int
+	c := q.Cursor()
+	// cur is the current item if onItem, otherwise the item after the gap,
+	// or -1 for the gap after the back.
+	cur, onItem := -1, false
+	follower := func(x int) int {
+		if i := slices.Index(model, x); i+1 < len(model) {
+			return model[i+1]
+		}
+		return -1
+	}
+	pos := func() int {
+		if cur == -1 {
+			return len(model)
+		}
+		return slices.Index(model, cur)
+	}
+
+	for round := range 20000 {
+		switch op := rng.IntN(12); {
+		case op < 3:
+			p := pos()
+			if onItem {
+				p++
+			}
+			if c.Next() != (p < len(model)) {
+				t.Fatalf("round %d: wrong result from Next", round)
+			}
+			if p < len(model) {
+				cur, onItem = model[p], true
+			} else {
+				cur, onItem = -1, false
+			}
+		case op < 5:
+			p := pos()
+			if c.Prev() != (p > 0) {
+				t.Fatalf("round %d: wrong result from Prev", round)
+			}
+			if p > 0 {
+				cur, onItem = model[p-1], true
+			} else if len(model) != 0 {
+				cur, onItem = model[0], false
+			}
+		case op == 5 && onItem:
+			if c.Value() != cur {
+				t.Fatalf("round %d: wrong value", round)
+			}
+			next++
+			c.Set(next)
+			model[pos()] = next
+			cur = next
+		case op == 6 && onItem:
+			f := follower(cur)
+			if c.Remove() != cur {
+				t.Fatalf("round %d: wrong value removed", round)
+			}
+			model = slices.Delete(model, pos(), pos()+1)
+			cur, onItem = f, false
+		case op < 9 || op > 9:
+			next++
+			at := pos()
+			before := op < 9
+			if before {
+				c.InsertBefore(next)
+			} else {
+				if onItem {
+					at++
+				} else {
+					cur = next
+				}
+				c.InsertAfter(next)
+			}
+			model = slices.Insert(model, at, next)
+			if maxLen != 0 && len(model) > maxLen {
+				// Pushing onto the front evicts the back item, otherwise the
+				// front item is evicted.
+				e := model[0]
+				if at == 0 {
+					e = model[len(model)-1]
+				}
+				if cur == e {
+					cur, onItem = follower(e), false
+				}
+				model = slices.DeleteFunc(model, func(x int) bool { return x == e })
+			}
+		default:
+			if !slices.Equal(q.AppendToSlice(nil), model) {
+				t.Fatalf("round %d: wrong contents", round)
+			}
+		}
+		if c.i != pos() || c.ok != onItem {
+			t.Fatalf("round %d, maxLen %d: cursor at %d %v, expected %d %v", round, maxLen, c.i, c.ok, pos(), onItem)
+		}
+		if q.Len() != len(model) {
+			t.Fatalf("round %d: length %d, expected %d", round, q.Len(), len(model))
+		}
+		// Favor removal once the deque is large.
+		if maxLen == 0 && len(model) > 200 {
+			for range 150 {
+				if !c.Next() {
+					c = q.Cursor()
+					cur, onItem = -1, false
+					c.Next()
+				}
+				c.Remove()
+			}
+			c = q.Cursor()
+			model = q.AppendToSlice(model[:0])
+			cur, onItem = -1, false
+			if len(model) != 0 {
+				cur = model[0]
+			}
+		}
+	}
+}
+
+func BenchmarkCursorRemove(b *testing.B) {
+	var q Deque[int]
+	for i := 0; i < b.N; i++ {
+		for j := range 1000 {
+			q.PushBack(j)
+		}
+		c := q.Cursor()
+		for c.Next() {
+			if c.Value()%3 == 0 {
+				c.Remove()
+			}
+		}
+		q.Clear()
+	}
+}
@@ -3,12 +3,18 @@ package deque
 import (
 	"fmt"
 	"iter"
+	"slices"
 )
 
 // minCapacity is the smallest capacity that deque may have. Must be power of 2
 // for bitwise modulus: x % n == x & (n - 1).
 const minCapacity = 16
 
+// maxBackMove is the largest number of items that ringMove moves toward the
+// back of the buffer with a single copy. A very large overlapping copy toward
+// higher addresses is much slower than the same copy done in smaller blocks.
+const maxBackMove = 1024
+
 // Deque represents a single instance of the deque data structure. A Deque
 // instance contains items of the type specified by the type argument.
 //
@@ -32,12 +38,190 @@ const minCapacity = 16
 //
 // Any values supplied to [SetBaseCap] and [Grow] are rounded up to the nearest
 // power of 2, since the Deque grows by powers of 2.
+//
+// To use the Deque as a fixed-size history, where adding an item to a full
+// Deque evicts the oldest item from the opposite end, set a maximum length:
+//
+//	d.SetMaxLen(100, deque.OverflowDrop)
+//
+// To shorten the pause caused by copying all items when a large Deque resizes,
+// enable incremental resizing:
+//
+//	d.SetIncrementalResize(true)
+//
+// To have the Deque use a buffer provided by the caller, instead of allocating
+// one, use [NewFromBuffer], or [NewFixed] for a Deque that never allocates.
 type Deque[T any] struct {
 	buf    []T
 	head   int
 	tail   int
 	count  int
 	minCap int
+	// ver is incremented by every change to the items or to their positions
+	// in the buffer, so that iterators can detect modification.
+	ver uint
+
+	// opts holds the settings that most deques do not use, or is nil if none
+	// have been set.
+	opts *options[T]
+
+	// old holds the state of an incremental resize, or is nil if no resize is
+	// in progress.
+	old *resizeState[T]
+
+	// shrink holds the shrink policy and its state, or is nil for the default
+	// policy.
+	shrink *shrinkState
+}
+
+// options holds the settings of a Deque that most deques do not use, so that
+// they do not add to the size of every Deque.
+type options[T any] struct {
+	maxLen   int
+	overflow OverflowPolicy
+	onEvict  func(T)
+	// fixed is set for a Deque created by NewFixed, which never replaces its
+	// buffer.
+	fixed bool
+
+	// alloc provides buffers when set. ext is a buffer provided by the caller,
+	// which is cleared instead of freed when it is no longer used. A Deque
+	// that has not had a buffer, and has no base capacity, uses ext as its
+	// first buffer if ext is large enough.
+	alloc Allocator[T]
+	ext   []T
+
+	// incremental is set if incremental resizing is enabled. The resize state
+	// is kept for reuse by each incremental resize.
+	incremental bool
+	resize      *resizeState[T]
+
+	// reserved records the positions returned by the last call to Reserve or
+	// ReserveFront.
+	reserved reservation
+}
+
+// reservation records the number of positions returned by Reserve, or by
+// ReserveFront if front is set. The positions can be committed only while the
+// version of the Deque is ver, which is until the Deque is next modified. The
+// zero value is no reservation.
+type reservation struct {
+	n     int
+	ver   uint
+	front bool
+	ok    bool
+}
+
+// resizeState is the state of an incremental resize. The items at buffer
+// positions lo through hi-1 have not yet been moved from buf, which is the old
+// buffer. The item for buffer position p is at buf[(head+p)&mask].
+type resizeState[T any] struct {
+	buf    []T
+	head   int
+	mask   int
+	lo, hi int
+}
+
+// OverflowPolicy determines what happens when an item is added to a Deque that
+// already holds its maximum number of items, as set by [Deque.SetMaxLen].
+type OverflowPolicy int
+
+const (
+	// OverflowDrop makes room for a new item by evicting the item at the
+	// opposite end of the deque from where the new item is added. An item
+	// pushed onto the back evicts the front item, and an item pushed onto the
+	// front evicts the back item. An item inserted into the middle of the
+	// deque evicts the front item.
+	OverflowDrop OverflowPolicy = iota
+	// OverflowReject discards the new item, leaving the deque unchanged.
+	OverflowReject
+	// OverflowPanic panics with an error wrapping [ErrFull], leaving the deque
+	// unchanged.
+	OverflowPanic
+)
+
+// ShrinkPolicy determines when a Deque reduces its capacity as items are
+// removed, as set by [Deque.SetShrinkPolicy]. The zero value is the default
+// policy, which halves the capacity when a removal leaves the Deque a quarter
+// full. The capacity is never reduced below the base capacity.
+//
+// A workload whose length repeatedly rises above and falls below a quarter of
+// the capacity causes the default policy to shrink and then grow again each
+// time. A lower FillRatio, a Delay, or an Adaptive policy avoids this.
+type ShrinkPolicy struct {
+	// Never disables shrinking when items are removed. The capacity is then
+	// only reduced by [Deque.ShrinkToFit].
+	Never bool
+	// FillRatio is the fraction of the capacity that is in use at or below
+	// which the Deque shrinks. Zero selects the default of 0.25, and values
+	// greater than 0.5 are treated as 0.5. The Deque shrinks to the smallest
+	// capacity that is at most half full.
+	FillRatio float64
+	// Delay is the number of consecutive removals that must leave the Deque at
+	// or below the fill ratio before it shrinks.
+	Delay int
+	// Adaptive keeps the capacity large enough for the high-water mark, which
+	// is the largest length reached recently. The high-water mark is halved
+	// after each period, as many removals as the capacity, in which it is not
+	// reached again. This suits workloads that repeatedly fill and drain the
+	// Deque.
+	Adaptive bool
+}
+
+// shrinkState holds a ShrinkPolicy and the observations it is based on.
+type shrinkState struct {
+	policy ShrinkPolicy
+	// low is the number of consecutive removals that left the deque at or
+	// below the fill ratio.
+	low int
+	// peak is the high-water mark, and since is the number of removals since
+	// it was last reached or halved.
+	peak  int
+	since int
+}
+
+// NewFromBuffer returns an empty Deque that uses buf as its buffer, so that
+// the memory for the buffer can be provided by the caller, such as from a
+// preallocated arena. The length of buf is the capacity of the Deque, and must
+// be a power of two, otherwise NewFromBuffer panics with an error wrapping
+// [ErrBufferSize]. Any items in buf are cleared.
+//
+// The length of buf is also the base capacity of the Deque, so the Deque keeps
+// using buf until more items are added than buf can hold. The Deque then grows
+// by allocating a new buffer, as usual, and buf is cleared and no longer used.
+// To prevent this, use [NewFixed].
+func NewFromBuffer[T any](buf []T) *Deque[T] {
+	if len(buf) == 0 || len(buf)&(len(buf)-1) != 0 {
+		panic(fmt.Errorf("%w: NewFromBuffer called with length %d", ErrBufferSize, len(buf)))
+	}
+	clear(buf)
+	return &Deque[T]{
+		buf:    buf,
+		minCap: len(buf),
+		opts:   &options[T]{ext: buf},
+	}
+}
+
+// NewFixed returns an empty Deque that uses buf as its buffer, as with
+// [NewFromBuffer], and never allocates another buffer. The maximum length of
+// the Deque is the length of buf, and policy determines what happens when an
+// item is added to a full Deque, as with [Deque.SetMaxLen].
+//
+// Since the buffer is never replaced, the Deque does not allocate after it is
+// created. [Deque.SetBaseCap] has no effect on a fixed Deque, and
+// [Deque.SetMaxLen] cannot set a maximum length greater than len(buf).
+// [Deque.Grow], [Deque.Reserve], and [Deque.ReserveFront] panic with an error
+// wrapping [ErrFull] if the buffer does not have room for the requested number
+// of items.
+func NewFixed[T any](buf []T, policy OverflowPolicy) *Deque[T] {
+	if len(buf) == 0 || len(buf)&(len(buf)-1) != 0 {
+		panic(fmt.Errorf("%w: NewFixed called with length %d", ErrBufferSize, len(buf)))
+	}
+	q := NewFromBuffer(buf)
+	q.opts.fixed = true
+	q.opts.maxLen = len(buf)
+	q.opts.overflow = policy
+	return q
 }
 
 // Cap returns the current capacity of the Deque. If q is nil, q.Cap() is zero.
@@ -61,42 +245,130 @@ func (q *Deque[T]) Len() int {
 // elements are removed with [PopFront], and LIFO when elements are removed with
 // [PopBack].
 func (q *Deque[T]) PushBack(elem T) {
+	if n := q.maxLen(); n != 0 && q.count >= n && !q.makeRoom(elem, false) {
+		return
+	}
 	q.growIfFull()
 
 	q.buf[q.tail] = elem
 	// Calculate new tail position.
 	q.tail = q.next(q.tail)
 	q.count++
+	q.ver++
+	if q.old != nil {
+		q.migrate()
+	}
 }
 
 // PushFront prepends an element to the front of the queue.
 func (q *Deque[T]) PushFront(elem T) {
+	if n := q.maxLen(); n != 0 && q.count >= n && !q.makeRoom(elem, true) {
+		return
+	}
 	q.growIfFull()
 
 	// Calculate new head position.
 	q.head = q.prev(q.head)
 	q.buf[q.head] = elem
 	q.count++
+	q.ver++
+	if q.old != nil {
+		q.migrate()
+	}
+}
+
+// PushBackSlice appends all the items in the given slice to the back of the
+// queue, in the order they appear in the slice.
+//
+//	q.PushBackSlice(items)
+//
+// is an efficient shortcut for
+//
+//	for _, item := range items {
+//		q.PushBack(item)
+//	}
+//
+// Capacity is reserved once for all the items, and the items are copied into
+// the buffer with at most two block copies.
+func (q *Deque[T]) PushBackSlice(items []T) {
+	if q.maxLen() != 0 {
+		items = q.admit(items, q.count, false)
+	}
+	if len(items) == 0 {
+		return
+	}
+	q.Grow(len(items))
+	q.copyToBuf(q.tail, items)
+	q.count += len(items)
+	q.ver++
+	q.tail = (q.tail + len(items)) & (len(q.buf) - 1) // bitwise modulus
+}
+
+// PushFrontSlice prepends all the items in the given slice to the front of the
+// queue, preserving their order. After the call, q.At(i) is items[i] for each
+// index of items.
+//
+//	q.PushFrontSlice(items)
+//
+// is an efficient shortcut for
+//
+//	for i := len(items) - 1; i >= 0; i-- {
+//		q.PushFront(items[i])
+//	}
+//
+// Capacity is reserved once for all the items, and the items are copied into
+// the buffer with at most two block copies.
+func (q *Deque[T]) PushFrontSlice(items []T) {
+	if q.maxLen() != 0 {
+		items = q.admit(items, q.count, true)
+	}
+	if len(items) == 0 {
+		return
+	}
+	q.Grow(len(items))
+	q.head = (q.head - len(items)) & (len(q.buf) - 1) // bitwise modulus
+	q.copyToBuf(q.head, items)
+	q.count += len(items)
+	q.ver++
+}
+
+// PushBackSeq appends all the items from the given iterator to the back of
+// the queue, in the order they are yielded. The items are collected before
+// being added with [PushBackSlice].
+func (q *Deque[T]) PushBackSeq(seq iter.Seq[T]) {
+	q.PushBackSlice(slices.Collect(seq))
+}
+
+// PushFrontSeq prepends all the items from the given iterator to the front of
+// the queue, preserving the order in which they are yielded. The items are
+// collected before being added with [PushFrontSlice].
+func (q *Deque[T]) PushFrontSeq(seq iter.Seq[T]) {
+	q.PushFrontSlice(slices.Collect(seq))
 }
 
 // PopFront removes and returns the element from the front of the queue.
 // Implements FIFO when used with [PushBack]. If the queue is empty, the call
-// panics.
+// panics with an error wrapping [ErrEmpty].
 func (q *Deque[T]) PopFront() T {
 	if q.count <= 0 {
-		panic("deque: PopFront() called on empty queue")
+		panic(fmt.Errorf("%w: PopFront() called", ErrEmpty))
 	}
-	ret := q.buf[q.head]
-	var zero T
-	q.buf[q.head] = zero
-	// Calculate new head position.
-	q.head = q.next(q.head)
-	q.count--
-
+	ret := q.popFront()
 	q.shrinkIfExcess()
 	return ret
 }
 
+// TryPopFront removes and returns the element from the front of the queue.
+// If the queue is empty, ok is false and the zero value is returned.
+func (q *Deque[T]) TryPopFront() (elem T, ok bool) {
+	if q.Len() == 0 {
+		return elem, false
+	}
+	elem = q.popFront()
+	q.shrinkIfExcess()
+	return elem, true
+}
+
 // IterPopFront returns an iterator that iteratively removes items from the
 // front of the deque. This is more efficient than removing items one at a time
 // because it avoids intermediate resizing. If a resize is necessary, only one
@@ -106,13 +378,26 @@ func (q *Deque[T]) IterPopFront() iter.Seq[T] {
 		if q.Len() == 0 {
 			return
 		}
-		var zero T
 		for q.count != 0 {
-			ret := q.buf[q.head]
-			q.buf[q.head] = zero
-			q.head = q.next(q.head)
-			q.count--
-			if !yield(ret) {
+			if !yield(q.popFront()) {
+				break
+			}
+		}
+		q.shrinkToFit()
+	}
+}
+
+// PopFrontWhile returns an iterator that removes items from the front of the
+// deque while the front item satisfies pred. The first item that does not
+// satisfy pred is left at the front of the deque. As with [Deque.IterPopFront],
+// if a resize is necessary, only one is done when iteration ends.
+func (q *Deque[T]) PopFrontWhile(pred func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if q.Len() == 0 {
+			return
+		}
+		for q.count != 0 && pred(*q.slot(q.head)) {
+			if !yield(q.popFront()) {
 				break
 			}
 		}
@@ -122,25 +407,27 @@ func (q *Deque[T]) IterPopFront() iter.Seq[T] {
 
 // PopBack removes and returns the element from the back of the queue.
 // Implements LIFO when used with [PushBack]. If the queue is empty, the call
-// panics.
+// panics with an error wrapping [ErrEmpty].
 func (q *Deque[T]) PopBack() T {
 	if q.count <= 0 {
-		panic("deque: PopBack() called on empty queue")
+		panic(fmt.Errorf("%w: PopBack() called", ErrEmpty))
 	}
-
-	// Calculate new tail position
-	q.tail = q.prev(q.tail)
-
-	// Remove value at tail.
-	ret := q.buf[q.tail]
-	var zero T
-	q.buf[q.tail] = zero
-	q.count--
-
+	ret := q.popBack()
 	q.shrinkIfExcess()
 	return ret
 }
 
+// TryPopBack removes and returns the element from the back of the queue. If
+// the queue is empty, ok is false and the zero value is returned.
+func (q *Deque[T]) TryPopBack() (elem T, ok bool) {
+	if q.Len() == 0 {
+		return elem, false
+	}
+	elem = q.popBack()
+	q.shrinkIfExcess()
+	return elem, true
+}
+
 // IterPopBack returns an iterator that iteratively removes items from the back
 // of the deque. This is more efficient than removing items one at a time
 // because it avoids intermediate resizing. If a resize is necessary, only one
@@ -150,13 +437,26 @@ func (q *Deque[T]) IterPopBack() iter.Seq[T] {
 		if q.Len() == 0 {
 			return
 		}
-		var zero T
 		for q.count != 0 {
-			q.tail = q.prev(q.tail)
-			ret := q.buf[q.tail]
-			q.buf[q.tail] = zero
-			q.count--
-			if !yield(ret) {
+			if !yield(q.popBack()) {
+				break
+			}
+		}
+		q.shrinkToFit()
+	}
+}
+
+// PopBackWhile returns an iterator that removes items from the back of the
+// deque while the back item satisfies pred. The first item that does not
+// satisfy pred is left at the back of the deque. As with [Deque.IterPopBack],
+// if a resize is necessary, only one is done when iteration ends.
+func (q *Deque[T]) PopBackWhile(pred func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if q.Len() == 0 {
+			return
+		}
+		for q.count != 0 && pred(*q.slot(q.prev(q.tail))) {
+			if !yield(q.popBack()) {
 				break
 			}
 		}
@@ -164,30 +464,151 @@ func (q *Deque[T]) IterPopBack() iter.Seq[T] {
 	}
 }
 
+// PopFrontInto removes items from the front of the queue and stores them in
+// dst, in the order they are removed, until dst is full or the queue is empty.
+// Returns the number of items removed.
+//
+//	n := q.PopFrontInto(dst)
+//
+// is an efficient shortcut for
+//
+//	n := min(len(dst), q.Len())
+//	for i := 0; i < n; i++ {
+//		dst[i] = q.PopFront()
+//	}
+//
+// Items are moved with block copies, and if a resize is necessary, only one is
+// done.
+func (q *Deque[T]) PopFrontInto(dst []T) int {
+	n := min(len(dst), q.Len())
+	if n == 0 {
+		return 0
+	}
+	q.settle()
+	q.copyFromBuf(dst[:n], q.head)
+	q.clearBuf(q.head, n)
+	q.head = (q.head + n) & (len(q.buf) - 1) // bitwise modulus
+	q.count -= n
+	q.ver++
+	q.shrinkToFit()
+	return n
+}
+
+// PopBackInto removes items from the back of the queue and stores them in
+// dst, in the order they are removed, until dst is full or the queue is empty.
+// Returns the number of items removed.
+//
+//	n := q.PopBackInto(dst)
+//
+// is an efficient shortcut for
+//
+//	n := min(len(dst), q.Len())
+//	for i := 0; i < n; i++ {
+//		dst[i] = q.PopBack()
+//	}
+//
+// Items are moved with block copies, and if a resize is necessary, only one is
+// done.
+func (q *Deque[T]) PopBackInto(dst []T) int {
+	n := min(len(dst), q.Len())
+	if n == 0 {
+		return 0
+	}
+	q.settle()
+	q.tail = (q.tail - n) & (len(q.buf) - 1) // bitwise modulus
+	q.copyFromBuf(dst[:n], q.tail)
+	slices.Reverse(dst[:n])
+	q.clearBuf(q.tail, n)
+	q.count -= n
+	q.ver++
+	q.shrinkToFit()
+	return n
+}
+
+// DiscardFront removes up to n items from the front of the queue without
+// returning them. Returns the number of items removed, which is the minimum of
+// n and q.Len(). If n is negative, DiscardFront panics with an error wrapping
+// [ErrNegativeCount]. If a resize is necessary, only one is done.
+func (q *Deque[T]) DiscardFront(n int) int {
+	if n < 0 {
+		panic(fmt.Errorf("%w: DiscardFront(%d) called", ErrNegativeCount, n))
+	}
+	n = min(n, q.Len())
+	if n == 0 {
+		return 0
+	}
+	q.settle()
+	q.clearBuf(q.head, n)
+	q.head = (q.head + n) & (len(q.buf) - 1) // bitwise modulus
+	q.count -= n
+	q.ver++
+	q.shrinkToFit()
+	return n
+}
+
+// DiscardBack removes up to n items from the back of the queue without
+// returning them. Returns the number of items removed, which is the minimum of
+// n and q.Len(). If n is negative, DiscardBack panics with an error wrapping
+// [ErrNegativeCount]. If a resize is necessary, only one is done.
+func (q *Deque[T]) DiscardBack(n int) int {
+	if n < 0 {
+		panic(fmt.Errorf("%w: DiscardBack(%d) called", ErrNegativeCount, n))
+	}
+	n = min(n, q.Len())
+	if n == 0 {
+		return 0
+	}
+	q.settle()
+	q.tail = (q.tail - n) & (len(q.buf) - 1) // bitwise modulus
+	q.clearBuf(q.tail, n)
+	q.count -= n
+	q.ver++
+	q.shrinkToFit()
+	return n
+}
+
 // Front returns the element at the front of the queue. This is the element
 // that would be returned by [PopFront]. This call panics if the queue is
 // empty.
 func (q *Deque[T]) Front() T {
 	if q.count <= 0 {
-		panic("deque: Front() called when empty")
+		panic(fmt.Errorf("%w: Front() called", ErrEmpty))
+	}
+	return *q.slot(q.head)
+}
+
+// TryFront returns the element at the front of the queue. If the queue is
+// empty, ok is false and the zero value is returned.
+func (q *Deque[T]) TryFront() (elem T, ok bool) {
+	if q.Len() == 0 {
+		return elem, false
 	}
-	return q.buf[q.head]
+	return *q.slot(q.head), true
 }
 
 // Back returns the element at the back of the queue. This is the element that
 // would be returned by [PopBack]. This call panics if the queue is empty.
 func (q *Deque[T]) Back() T {
 	if q.count <= 0 {
-		panic("deque: Back() called when empty")
+		panic(fmt.Errorf("%w: Back() called", ErrEmpty))
+	}
+	return *q.slot(q.prev(q.tail))
+}
+
+// TryBack returns the element at the back of the queue. If the queue is empty,
+// ok is false and the zero value is returned.
+func (q *Deque[T]) TryBack() (elem T, ok bool) {
+	if q.Len() == 0 {
+		return elem, false
 	}
-	return q.buf[q.prev(q.tail)]
+	return *q.slot(q.prev(q.tail)), true
 }
 
 // At returns the element at index i in the queue without removing the element
 // from the queue. This method accepts only non-negative index values. At(0)
 // refers to the first element and is the same as [Front]. At(Len()-1) refers
 // to the last element and is the same as [Back]. If the index is invalid, the
-// call panics.
+// call panics with an [*IndexError].
 //
 // The purpose of At is to allow Deque to serve as a more general purpose
 // circular buffer, where items are only added to and removed from the ends of
@@ -198,7 +619,28 @@ func (q *Deque[T]) Back() T {
 func (q *Deque[T]) At(i int) T {
 	q.checkRange(i)
 	// bitwise modulus
-	return q.buf[(q.head+i)&(len(q.buf)-1)]
+	p := (q.head + i) & (len(q.buf) - 1)
+	// Same as slot, written out so that At can be inlined.
+	if r := q.old; r != nil && p >= r.lo && p < r.hi {
+		return r.buf[(r.head+p)&r.mask]
+	}
+	return q.buf[p]
+}
+
+// TryAt returns the element at index i in the queue without removing the
+// element from the queue. If the index is invalid, ok is false and the zero
+// value is returned.
+func (q *Deque[T]) TryAt(i int) (elem T, ok bool) {
+	if !q.inRange(i) {
+		return elem, false
+	}
+	// bitwise modulus
+	p := (q.head + i) & (len(q.buf) - 1)
+	// Same as slot, written out so that TryAt can be inlined.
+	if r := q.old; r != nil && p >= r.lo && p < r.hi {
+		return r.buf[(r.head+p)&r.mask], true
+	}
+	return q.buf[p], true
 }
 
 // Set assigns the item to index i in the queue. Set indexes the deque the same
@@ -207,22 +649,48 @@ func (q *Deque[T]) At(i int) T {
 func (q *Deque[T]) Set(i int, item T) {
 	q.checkRange(i)
 	// bitwise modulus
-	q.buf[(q.head+i)&(len(q.buf)-1)] = item
+	p := (q.head + i) & (len(q.buf) - 1)
+	// Same as slot, written out so that Set can be inlined.
+	if r := q.old; r != nil && p >= r.lo && p < r.hi {
+		r.buf[(r.head+p)&r.mask] = item
+	} else {
+		q.buf[p] = item
+	}
+	q.ver++
+}
+
+// TrySet assigns the item to index i in the queue. If the index is invalid,
+// the queue is not modified and false is returned.
+func (q *Deque[T]) TrySet(i int, item T) bool {
+	if !q.inRange(i) {
+		return false
+	}
+	// bitwise modulus
+	p := (q.head + i) & (len(q.buf) - 1)
+	// Same as slot, written out so that TrySet can be inlined.
+	if r := q.old; r != nil && p >= r.lo && p < r.hi {
+		r.buf[(r.head+p)&r.mask] = item
+	} else {
+		q.buf[p] = item
+	}
+	q.ver++
+	return true
 }
 
 // Iter returns a go iterator to range over all items in the Deque, yielding
 // each item from front (index 0) to back (index Len()-1). Modification of
-// Deque during iteration panics.
+// Deque during iteration panics with [ErrModifiedDuringIteration]. Every method
+// that changes the items or their order, including [Deque.Set] and
+// [Deque.Swap], is a modification.
 func (q *Deque[T]) Iter() iter.Seq[T] {
 	return func(yield func(T) bool) {
-		origHead := q.head
-		origTail := q.tail
-		head := origHead
+		ver := q.ver
+		head := q.head
 		for range q.Len() {
-			if q.head != origHead || q.tail != origTail {
-				panic("deque: modified during iteration")
+			if q.ver != ver {
+				panic(ErrModifiedDuringIteration)
 			}
-			if !yield(q.buf[head]) {
+			if !yield(*q.slot(head)) {
 				return
 			}
 			head = q.next(head)
@@ -232,18 +700,117 @@ func (q *Deque[T]) Iter() iter.Seq[T] {
 
 // RIter returns a reverse go iterator to range over all items in the Deque,
 // yielding each item from back (index Len()-1) to front (index 0).
-// Modification of Deque during iteration panics.
+// Modification of Deque during iteration panics with
+// [ErrModifiedDuringIteration].
 func (q *Deque[T]) RIter() iter.Seq[T] {
 	return func(yield func(T) bool) {
-		origHead := q.head
-		origTail := q.tail
-		tail := origTail
+		ver := q.ver
+		tail := q.tail
 		for range q.Len() {
-			if q.head != origHead || q.tail != origTail {
-				panic("deque: modified during iteration")
+			if q.ver != ver {
+				panic(ErrModifiedDuringIteration)
+			}
+			tail = q.prev(tail)
+			if !yield(*q.slot(tail)) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns a go iterator that yields each item from front to back. It is
+// the same as [Deque.Iter], and is named to match [slices.Values].
+func (q *Deque[T]) Values() iter.Seq[T] {
+	return q.Iter()
+}
+
+// All returns a go iterator over the index and item of each item in the Deque,
+// from front (index 0) to back (index Len()-1), as [slices.All] does for a
+// slice. Modification of Deque during iteration panics with
+// [ErrModifiedDuringIteration].
+//
+//	for i, item := range q.All() {
+//		fmt.Println(i, item)
+//	}
+func (q *Deque[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		ver := q.ver
+		head := q.head
+		for i := range q.Len() {
+			if q.ver != ver {
+				panic(ErrModifiedDuringIteration)
+			}
+			if !yield(i, *q.slot(head)) {
+				return
+			}
+			head = q.next(head)
+		}
+	}
+}
+
+// Backward returns a go iterator over the index and item of each item in the
+// Deque, from back (index Len()-1) to front (index 0), as [slices.Backward]
+// does for a slice. Modification of Deque during iteration panics with
+// [ErrModifiedDuringIteration].
+func (q *Deque[T]) Backward() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		ver := q.ver
+		tail := q.tail
+		for i := q.Len() - 1; i >= 0; i-- {
+			if q.ver != ver {
+				panic(ErrModifiedDuringIteration)
 			}
 			tail = q.prev(tail)
-			if !yield(q.buf[tail]) {
+			if !yield(i, *q.slot(tail)) {
+				return
+			}
+		}
+	}
+}
+
+// IterRange returns a go iterator over the index and item of each item in the
+// Deque from index from through index to-1, in that order. When iteration
+// starts, if from is negative, or to is less than from or greater than Len(),
+// the iterator panics with an [*IndexError]. Modification of Deque during
+// iteration panics with [ErrModifiedDuringIteration].
+func (q *Deque[T]) IterRange(from, to int) iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		q.checkSlice(from, to)
+		if from == to {
+			return
+		}
+		ver := q.ver
+		pos := (q.head + from) & (len(q.buf) - 1) // bitwise modulus
+		for i := from; i < to; i++ {
+			if q.ver != ver {
+				panic(ErrModifiedDuringIteration)
+			}
+			if !yield(i, *q.slot(pos)) {
+				return
+			}
+			pos = q.next(pos)
+		}
+	}
+}
+
+// RIterRange returns a go iterator over the index and item of each item in the
+// Deque from index to-1 down to index from. The range is checked as with
+// [Deque.IterRange]. Modification of Deque during iteration panics with
+// [ErrModifiedDuringIteration].
+func (q *Deque[T]) RIterRange(from, to int) iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		q.checkSlice(from, to)
+		if from == to {
+			return
+		}
+		ver := q.ver
+		pos := (q.head + to) & (len(q.buf) - 1) // bitwise modulus
+		for i := to - 1; i >= from; i-- {
+			if q.ver != ver {
+				panic(ErrModifiedDuringIteration)
+			}
+			pos = q.prev(pos)
+			if !yield(i, *q.slot(pos)) {
 				return
 			}
 		}
@@ -263,6 +830,8 @@ func (q *Deque[T]) Clear() {
 	q.count = 0
 	q.head = 0
 	q.tail = 0
+	q.ver++
+	q.endResize()
 
 	if head >= tail {
 		// [DEF....ABC]
@@ -274,10 +843,11 @@ func (q *Deque[T]) Clear() {
 
 // Grow grows deque's capacity, if necessary, to guarantee space for another n
 // items. After Grow(n), at least n items can be written to the deque without
-// another allocation. If n is negative, Grow panics.
+// another allocation. If n is negative, Grow panics with an error wrapping
+// [ErrNegativeCount].
 func (q *Deque[T]) Grow(n int) {
 	if n < 0 {
-		panic("deque.Grow: negative count")
+		panic(fmt.Errorf("%w: Grow(%d) called", ErrNegativeCount, n))
 	}
 	c := q.Cap()
 	l := q.Len()
@@ -285,17 +855,21 @@ func (q *Deque[T]) Grow(n int) {
 	if n <= c-l {
 		return
 	}
-
-	if c == 0 {
-		c = minCapacity
+	if q.isFixed() {
+		panic(fmt.Errorf("%w: Grow(%d) exceeds fixed capacity %d", ErrFull, n, c))
 	}
 
 	newLen := l + n
+	if c == 0 {
+		q.buf = q.firstBuf(newLen)
+		return
+	}
 	for c < newLen {
 		c <<= 1
 	}
 	if l == 0 {
-		q.buf = make([]T, c)
+		q.freeBuf(q.buf)
+		q.buf = q.newBuf(c)
 		q.head = 0
 		q.tail = 0
 	} else {
@@ -303,6 +877,16 @@ func (q *Deque[T]) Grow(n int) {
 	}
 }
 
+// ShrinkToFit reduces the capacity of the Deque to the smallest power of two
+// that holds its items, but not below the base capacity. This is done
+// regardless of the shrink policy, and is useful when a Deque that never
+// shrinks, as set by [Deque.SetShrinkPolicy], is no longer expected to grow.
+func (q *Deque[T]) ShrinkToFit() {
+	if c := q.fitCap(q.count); c < len(q.buf) {
+		q.shrinkTo(c)
+	}
+}
+
 // Copy copies the contents of the given src Deque into this Deque.
 //
 //	n := b.Copy(a)
@@ -316,12 +900,16 @@ func (q *Deque[T]) Grow(n int) {
 //		b.PushBack(a.At(i))
 //	}
 func (q *Deque[T]) Copy(src Deque[T]) int {
+	if n := q.maxLen(); n != 0 && src.Len() > n {
+		return q.copyLimited(src)
+	}
 	q.Clear()
 	q.Grow(src.Len())
 	n := src.CopyOutSlice(q.buf)
 	q.count = n
 	q.tail = n & (len(q.buf) - 1) // bitwise modulus
 	q.head = 0
+	q.ver++
 	return n
 }
 
@@ -340,6 +928,12 @@ func (q *Deque[T]) AppendToSlice(out []T) []T {
 	if q.count == 0 {
 		return out
 	}
+	if q.old != nil {
+		n := len(out)
+		out = slices.Grow(out, q.count)[:n+q.count]
+		q.copyFromBuf(out[n:], q.head)
+		return out
+	}
 
 	head, tail := q.head, q.tail
 
@@ -351,37 +945,207 @@ func (q *Deque[T]) AppendToSlice(out []T) []T {
 	return append(out, q.buf[head:tail]...)
 }
 
-// CopyInSlice replaces the contents of Deque with all the elements from the
-// given slice, in. If len(in) is zero, then this is equivalent to calling
-// [Clear].
+// AsSlices returns the contents of the Deque, without copying, as two slices
+// that refer to the Deque's internal buffer. The front slice holds the items
+// from the front of the Deque, and the back slice holds the remaining items,
+// so that the contents of the Deque are the items in front followed by the
+// items in back. If all the items are contiguous in the buffer, back is nil.
+// If the Deque is empty, both slices are nil.
 //
-//	q.CopyInSlice(in)
+// The slices must only be used to read the items. Use [Deque.AsMutSlices] to
+// modify items in place.
 //
-// is an efficient shortcut for
+// The slices are only valid until the next call to a method that modifies the
+// Deque. After that, they may refer to a buffer that is no longer used by the
+// Deque, or to positions that hold different items.
+func (q *Deque[T]) AsSlices() (front, back []T) {
+	q.settle()
+	return q.asSlices()
+}
+
+// AsMutSlices returns the contents of the Deque as two slices that refer to
+// the Deque's internal buffer, in the same way as [Deque.AsSlices]. Assigning
+// to an element of either slice replaces the corresponding item in the Deque,
+// as if by [Deque.Set].
 //
-//	q.Clear()
-//	for i := range in {
-//		q.PushBack(in[i])
-//	}
-func (q *Deque[T]) CopyInSlice(in []T) {
-	// Allocate new buffer if more space needed.
-	if len(q.buf) < len(in) {
-		newCap := len(q.buf)
-		if newCap == 0 {
-			newCap = minCapacity
-			q.minCap = minCapacity
-		}
-		for newCap < len(in) {
-			newCap <<= 1
-		}
-		q.buf = make([]T, newCap)
-	} else if len(q.buf) > len(in) {
-		q.Clear()
-	}
-	n := copy(q.buf, in)
-	q.count = n
+// The slices are only valid until the next call to a method that modifies the
+// Deque. Appending to either slice does not add items to the Deque.
+func (q *Deque[T]) AsMutSlices() (front, back []T) {
+	q.settle()
+	// The items may be modified through the slices.
+	q.ver++
+	return q.asSlices()
+}
+
+// MakeContiguous rearranges the internal buffer of the Deque, in place, so
+// that all the items are stored contiguously, and returns a slice that holds
+// the items in order from front to back. No new buffer is allocated. If the
+// items are already contiguous, they are not moved. If the Deque is empty,
+// MakeContiguous returns nil.
+//
+// The returned slice refers to the Deque's internal buffer, and assigning to
+// its elements replaces items in the Deque. The slice is only valid until the
+// next call to a method that modifies the Deque.
+func (q *Deque[T]) MakeContiguous() []T {
+	if q.Len() == 0 {
+		return nil
+	}
+	q.settle()
+	// The items may be modified through the returned slice.
+	q.ver++
+	if end := q.head + q.count; end <= len(q.buf) {
+		return q.buf[q.head:end:end]
+	}
+
+	// The items wrap around the end of the buffer: [DEF....ABC]
+	frontLen := len(q.buf) - q.head
+	if len(q.buf)-q.count >= frontLen {
+		// There is room to move DEF past ABC, then move ABC to the start:
+		// [...DEFABC] -> [ABCDEF...]
+		q.ringMove(frontLen, 0, q.tail)
+		copy(q.buf, q.buf[q.head:])
+		clear(q.buf[max(q.head, q.count):])
+	} else {
+		// Rotate the whole buffer left by head.
+		slices.Reverse(q.buf[:q.head])
+		slices.Reverse(q.buf[q.head:])
+		slices.Reverse(q.buf)
+	}
+	q.head = 0
+	q.tail = q.count & (len(q.buf) - 1) // bitwise modulus
+	return q.buf[:q.count:q.count]
+}
+
+// Reserve makes space for n items at the back of the Deque, and returns the
+// unused buffer positions where the items are to be written, so that a
+// producer can write items directly into the Deque's buffer. The positions are
+// returned as up to two slices, since the space may wrap around the end of the
+// buffer, and together hold exactly n positions. The items become part of the
+// Deque when [Deque.Commit] is called. If n is negative, Reserve panics with
+// an error wrapping [ErrNegativeCount].
+//
+// The buffer grows at most once to make space for the n items. The slices are
+// only valid until the next call to a method that modifies the Deque, other
+// than Commit.
+//
+//	a, b := q.Reserve(len(items))
+//	n := copy(a, items)
+//	copy(b, items[n:])
+//	q.Commit(len(items))
+//
+// is equivalent to q.PushBackSlice(items).
+func (q *Deque[T]) Reserve(n int) (a, b []T) {
+	if n < 0 {
+		panic(fmt.Errorf("%w: Reserve(%d) called", ErrNegativeCount, n))
+	}
+	if n == 0 {
+		q.reserve(0, false)
+		return nil, nil
+	}
+	q.settle()
+	q.Grow(n)
+	q.reserve(n, false)
+	return q.bufSlices(q.tail, n)
+}
+
+// Commit adds the first k items written to the positions returned by the
+// preceding call to [Deque.Reserve] to the back of the Deque. Commit panics
+// with an error wrapping [ErrOutOfRange] if k is negative or greater than the
+// number of reserved positions, or if the Deque was modified, or already
+// committed to, since the call to Reserve. The maximum length of the Deque is
+// applied to the committed items as if each had been added with
+// [Deque.PushBack].
+func (q *Deque[T]) Commit(k int) {
+	q.checkCommit(k, false)
+	q.tail = (q.tail + k) & (len(q.buf) - 1) // bitwise modulus
+	q.count += k
+	q.ver++
+	if n := q.maxLen(); n != 0 && q.count > n {
+		q.limitCommit(k, false)
+	}
+}
+
+// ReserveFront makes space for n items at the front of the Deque, and returns
+// the unused buffer positions where the items are to be written, in the same
+// way as [Deque.Reserve]. The returned positions are the n positions that
+// precede the front of the Deque, in order. The items become part of the Deque
+// when [Deque.CommitFront] is called.
+//
+//	a, b := q.ReserveFront(len(items))
+//	n := copy(a, items)
+//	copy(b, items[n:])
+//	q.CommitFront(len(items))
+//
+// is equivalent to q.PushFrontSlice(items).
+func (q *Deque[T]) ReserveFront(n int) (a, b []T) {
+	if n < 0 {
+		panic(fmt.Errorf("%w: ReserveFront(%d) called", ErrNegativeCount, n))
+	}
+	if n == 0 {
+		q.reserve(0, true)
+		return nil, nil
+	}
+	q.settle()
+	q.Grow(n)
+	q.reserve(n, true)
+	return q.bufSlices((q.head-n)&(len(q.buf)-1), n)
+}
+
+// CommitFront adds items written to the positions returned by the preceding
+// call to [Deque.ReserveFront] to the front of the Deque. The k items are the
+// last k of the reserved positions, which are the positions immediately
+// preceding the front of the Deque. CommitFront panics with an error wrapping
+// [ErrOutOfRange] if k is negative or greater than the number of reserved
+// positions, or if the Deque was modified, or already committed to, since the
+// call to ReserveFront. The maximum length of the Deque is applied to the
+// committed items as with [Deque.PushFrontSlice].
+func (q *Deque[T]) CommitFront(k int) {
+	q.checkCommit(k, true)
+	q.head = (q.head - k) & (len(q.buf) - 1) // bitwise modulus
+	q.count += k
+	q.ver++
+	if n := q.maxLen(); n != 0 && q.count > n {
+		q.limitCommit(k, true)
+	}
+}
+
+// CopyInSlice replaces the contents of Deque with all the elements from the
+// given slice, in. If len(in) is zero, then this is equivalent to calling
+// [Clear].
+//
+//	q.CopyInSlice(in)
+//
+// is an efficient shortcut for
+//
+//	q.Clear()
+//	for i := range in {
+//		q.PushBack(in[i])
+//	}
+func (q *Deque[T]) CopyInSlice(in []T) {
+	if q.maxLen() != 0 {
+		in = q.admit(in, 0, false)
+	}
+	// Allocate new buffer if more space needed.
+	if len(q.buf) == 0 {
+		if len(in) != 0 {
+			q.buf = q.firstBuf(len(in))
+		}
+	} else if len(q.buf) < len(in) {
+		newCap := len(q.buf)
+		for newCap < len(in) {
+			newCap <<= 1
+		}
+		q.freeBuf(q.buf)
+		q.buf = q.newBuf(newCap)
+	} else if len(q.buf) > len(in) {
+		q.Clear()
+	}
+	q.endResize()
+	n := copy(q.buf, in)
+	q.count = n
 	q.tail = n & (len(q.buf) - 1) // bitwise modulus
 	q.head = 0
+	q.ver++
 }
 
 // CopyOutSlice copies elements from the Deque into the given slice, up to the
@@ -404,6 +1168,11 @@ func (q *Deque[T]) CopyOutSlice(out []T) int {
 	if q.count == 0 || len(out) == 0 {
 		return 0
 	}
+	if q.old != nil {
+		n := min(len(out), q.count)
+		q.copyFromBuf(out[:n], q.head)
+		return n
+	}
 
 	head, tail := q.head, q.tail
 	var n int
@@ -426,6 +1195,9 @@ func (q *Deque[T]) CopyOutSlice(out []T) int {
 // back-to-front. Having Deque provide Rotate avoids resizing that could happen
 // if implementing rotation using only Pop and Push methods. If q.Len() is one
 // or less, or q is nil, then Rotate does nothing.
+//
+// Rotation is done in whichever direction moves fewer items, and items are
+// moved using block copies.
 func (q *Deque[T]) Rotate(n int) {
 	if q.Len() <= 1 {
 		return
@@ -435,6 +1207,14 @@ func (q *Deque[T]) Rotate(n int) {
 	if n == 0 {
 		return
 	}
+	// Rotate the shorter way around.
+	if n > q.count/2 {
+		n -= q.count
+	} else if n < -q.count/2 {
+		n += q.count
+	}
+	q.settle()
+	q.ver++
 
 	modBits := len(q.buf) - 1
 	// If no empty space in buffer, only move head and tail indexes.
@@ -445,30 +1225,28 @@ func (q *Deque[T]) Rotate(n int) {
 		return
 	}
 
-	var zero T
-
+	free := len(q.buf) - q.count
 	if n < 0 {
-		// Rotate back to front.
-		for ; n < 0; n++ {
-			// Calculate new head and tail using bitwise modulus.
-			q.head = (q.head - 1) & modBits
-			q.tail = (q.tail - 1) & modBits
-			// Put tail value at head and remove value at tail.
-			q.buf[q.head] = q.buf[q.tail]
-			q.buf[q.tail] = zero
-		}
+		// Rotate back to front: move the n back items to before the head.
+		n = -n
+		src := (q.tail - n) & modBits
+		q.ringMove((q.head-n)&modBits, src, n)
+		// Clear the moved-from positions that are now unused, which are
+		// the start of the source range unless it was overwritten.
+		q.clearBuf(src, min(n, free))
+		q.head = (q.head - n) & modBits
+		q.tail = src
 		return
 	}
 
-	// Rotate front to back.
-	for ; n > 0; n-- {
-		// Put head value at tail and remove value at head.
-		q.buf[q.tail] = q.buf[q.head]
-		q.buf[q.head] = zero
-		// Calculate new head and tail using bitwise modulus.
-		q.head = (q.head + 1) & modBits
-		q.tail = (q.tail + 1) & modBits
-	}
+	// Rotate front to back: move the n front items to after the tail.
+	q.ringMove(q.tail, q.head, n)
+	// Clear the moved-from positions that are now unused, which are the end of
+	// the source range unless it was overwritten.
+	v := min(n, free)
+	q.clearBuf((q.head+n-v)&modBits, v)
+	q.head = (q.head + n) & modBits
+	q.tail = (q.tail + n) & modBits
 }
 
 // Index returns the index into the Deque of the first item satisfying f(item),
@@ -478,7 +1256,7 @@ func (q *Deque[T]) Index(f func(T) bool) int {
 	if q.Len() > 0 {
 		modBits := len(q.buf) - 1
 		for i := 0; i < q.count; i++ {
-			if f(q.buf[(q.head+i)&modBits]) {
+			if f(*q.slot((q.head + i) & modBits)) {
 				return i
 			}
 		}
@@ -493,7 +1271,7 @@ func (q *Deque[T]) RIndex(f func(T) bool) int {
 	if q.Len() > 0 {
 		modBits := len(q.buf) - 1
 		for i := q.count - 1; i >= 0; i-- {
-			if f(q.buf[(q.head+i)&modBits]) {
+			if f(*q.slot((q.head + i) & modBits)) {
 				return i
 			}
 		}
@@ -509,7 +1287,8 @@ func (q *Deque[T]) RIndex(f func(T) bool) int {
 // Important: Deque is optimized for O(1) operations at the ends of the queue,
 // not for operations in the the middle. Complexity of this function is
 // constant plus linear in the lesser of the distances between the index and
-// either of the ends of the queue.
+// either of the ends of the queue. The items on that side are shifted with
+// block copies.
 func (q *Deque[T]) Insert(at int, item T) {
 	if at <= 0 {
 		q.PushFront(item)
@@ -519,24 +1298,29 @@ func (q *Deque[T]) Insert(at int, item T) {
 		q.PushBack(item)
 		return
 	}
-	if at*2 < q.count {
-		q.PushFront(item)
-		front := q.head
-		for range at {
-			next := q.next(front)
-			q.buf[front], q.buf[next] = q.buf[next], q.buf[front]
-			front = next
+	if n := q.maxLen(); n != 0 && q.count >= n {
+		if !q.makeRoom(item, false) {
+			return
 		}
-		return
+		// The front item was evicted, so the insertion point moved forward.
+		at--
 	}
-	swaps := q.count - at
-	q.PushBack(item)
-	back := q.prev(q.tail)
-	for range swaps {
-		prev := q.prev(back)
-		q.buf[back], q.buf[prev] = q.buf[prev], q.buf[back]
-		back = prev
+	q.growIfFull()
+	q.settle()
+	if at*2 < q.count {
+		// Move the items before the insertion point toward the front.
+		newHead := q.prev(q.head)
+		q.ringMove(newHead, q.head, at)
+		q.head = newHead
+	} else {
+		// Move the items after the insertion point toward the back.
+		pos := (q.head + at) & (len(q.buf) - 1)
+		q.ringMove(q.next(pos), pos, q.count-at)
+		q.tail = q.next(q.tail)
 	}
+	q.buf[(q.head+at)&(len(q.buf)-1)] = item
+	q.count++
+	q.ver++
 }
 
 // Remove removes and returns an element from the middle of the queue, at the
@@ -547,30 +1331,134 @@ func (q *Deque[T]) Insert(at int, item T) {
 // Important: Deque is optimized for O(1) operations at the ends of the queue,
 // not for operations in the the middle. Complexity of this function is
 // constant plus linear in the lesser of the distances between the index and
-// either of the ends of the queue.
+// either of the ends of the queue. The items on that side are shifted with
+// block copies.
 func (q *Deque[T]) Remove(at int) T {
 	q.checkRange(at)
-	rm := (q.head + at) & (len(q.buf) - 1)
-	if at*2 < q.count {
-		for range at {
-			prev := q.prev(rm)
-			q.buf[prev], q.buf[rm] = q.buf[rm], q.buf[prev]
-			rm = prev
-		}
-		return q.PopFront()
+	return q.remove(at)
+}
+
+// TryRemove removes and returns an element from the middle of the queue, at
+// the specified index. If the index is invalid, ok is false and the zero value
+// is returned.
+func (q *Deque[T]) TryRemove(at int) (elem T, ok bool) {
+	if !q.inRange(at) {
+		return elem, false
+	}
+	return q.remove(at), true
+}
+
+// InsertSlice inserts all the items into the middle of the queue, before the
+// element at the specified index, preserving their order. After the call,
+// q.At(at+i) is items[i] for each index of items. Out of range indexes result
+// in pushing the items onto the front or back of the deque.
+//
+//	q.InsertSlice(at, items...)
+//
+// is an efficient shortcut for
+//
+//	for i, item := range items {
+//		q.Insert(at+i, item)
+//	}
+//
+// If the Deque has a maximum length and the items do not fit, the overflow
+// policy applies to the deque as it would be after the insertion; when items
+// are dropped, they are dropped from the front.
+//
+// Complexity of this function is linear in the number of items plus the lesser
+// of the distances between the index and either of the ends of the queue.
+// Existing items are moved using block copies.
+func (q *Deque[T]) InsertSlice(at int, items ...T) {
+	at = max(0, min(at, q.Len()))
+	q.splice(at, 0, items)
+}
+
+// RemoveRange removes the elements from index from up to, but not including,
+// index to. Accepts only indexes where 0 <= from <= to <= q.Len(), and panics
+// with an [*IndexError] otherwise.
+//
+// Complexity of this function is linear in the number of items removed plus
+// the lesser of the distances between the removed range and either of the ends
+// of the queue. Remaining items are moved using block copies, and if a resize
+// is necessary, only one is done.
+//
+// Unlike [Deque.Splice], RemoveRange does not return the removed items, so
+// that it does not allocate. To get the removed items, use
+// Splice(from, to-from), which returns them in a new slice.
+func (q *Deque[T]) RemoveRange(from, to int) {
+	q.checkSlice(from, to)
+	q.splice(from, to-from, nil)
+}
+
+// Splice removes deleteCount elements starting at index at, and inserts the
+// given items in their place, preserving the order of the items. Returns the
+// removed elements, or nil if none were removed. Accepts only values where
+// 0 <= at and at+deleteCount <= q.Len(), and panics with an [*IndexError]
+// otherwise. If deleteCount is negative, Splice panics with an error wrapping
+// [ErrNegativeCount].
+//
+// Existing items are moved once, using block copies, on whichever side of the
+// spliced range is shorter. The maximum length of the Deque is applied as
+// described for [Deque.InsertSlice].
+func (q *Deque[T]) Splice(at, deleteCount int, items ...T) []T {
+	if deleteCount < 0 {
+		panic(fmt.Errorf("%w: Splice(%d, %d) called", ErrNegativeCount, at, deleteCount))
 	}
-	swaps := q.count - at - 1
-	for range swaps {
-		next := q.next(rm)
-		q.buf[rm], q.buf[next] = q.buf[next], q.buf[rm]
-		rm = next
+	q.checkSlice(at, at+deleteCount)
+	var removed []T
+	if deleteCount != 0 {
+		removed = make([]T, deleteCount)
+		q.copyFromBuf(removed, (q.head+at)&(len(q.buf)-1))
 	}
-	return q.PopBack()
+	q.splice(at, deleteCount, items)
+	return removed
+}
+
+// DeleteFunc removes all items for which del returns true, preserving the
+// order of the remaining items, and returns the number of items removed, as
+// [slices.DeleteFunc] does for a slice. The items are compacted in a single
+// pass, and if a resize is necessary, only one is done. If del modifies the
+// Deque, DeleteFunc panics with [ErrModifiedDuringIteration], and the contents
+// of the Deque are then unspecified, since some items may already have been
+// moved.
+//
+//	n := q.DeleteFunc(func(item T) bool { return item.expired() })
+//
+// is an efficient shortcut for
+//
+//	n := 0
+//	for i := q.Len() - 1; i >= 0; i-- {
+//		if q.At(i).expired() {
+//			q.Remove(i)
+//			n++
+//		}
+//	}
+func (q *Deque[T]) DeleteFunc(del func(T) bool) int {
+	return q.retain(func(item *T) bool { return !del(*item) })
+}
+
+// Retain removes all items for which keep returns false, preserving the order
+// of the remaining items, and returns the number of items removed. It is the
+// same as [Deque.DeleteFunc] with the result of the function negated.
+func (q *Deque[T]) Retain(keep func(T) bool) int {
+	return q.retain(func(item *T) bool { return keep(*item) })
+}
+
+// RetainMut is like [Deque.Retain], but calls keep with a pointer to each
+// item, so that an item can be updated as it is kept. The pointer must not be
+// retained after keep returns. Since items may be updated, RetainMut counts as
+// a modification of a non-empty Deque even if no items are removed.
+func (q *Deque[T]) RetainMut(keep func(item *T) bool) int {
+	return q.retain(keep)
 }
 
 // SetBaseCap sets a base capacity so that at least the specified number of
-// items can always be stored without resizing.
+// items can always be stored without resizing. SetBaseCap has no effect on a
+// Deque created by [NewFixed].
 func (q *Deque[T]) SetBaseCap(baseCap int) {
+	if q.isFixed() {
+		return
+	}
 	minCap := minCapacity
 	for minCap < baseCap {
 		minCap <<= 1
@@ -578,24 +1466,637 @@ func (q *Deque[T]) SetBaseCap(baseCap int) {
 	q.minCap = minCap
 }
 
+// SetIncrementalResize enables or disables incremental resizing. Normally,
+// when the Deque grows or shrinks, all items are copied to a new buffer by the
+// operation that triggers the resize. With incremental resizing, the old and
+// new buffers are both used until all items have been moved, and each push or
+// pop moves a small, fixed number of items. The push or pop that triggers a
+// resize still allocates the new buffer, which the runtime or the [Allocator]
+// zeroes in time proportional to its size, but it does not copy any items.
+// Zeroing a buffer is much faster than copying the items into it, so this
+// shortens the longest pause considerably, at the expense of slightly slower
+// access while a resize is in progress.
+//
+// Operations other than push, pop, and access to individual items complete
+// any resize that is in progress before they proceed. Disabling incremental
+// resizing completes any resize in progress.
+func (q *Deque[T]) SetIncrementalResize(enabled bool) {
+	if !enabled {
+		if q.opts != nil {
+			q.opts.incremental = false
+			q.settle()
+		}
+		return
+	}
+	o := q.opt()
+	o.incremental = true
+	if o.resize == nil {
+		o.resize = new(resizeState[T])
+	}
+}
+
+// SetShrinkPolicy sets the policy that determines when the Deque reduces its
+// capacity as items are removed. If policy.Delay is negative, SetShrinkPolicy
+// panics with an error wrapping [ErrNegativeCount].
+//
+//	q.SetShrinkPolicy(deque.ShrinkPolicy{Adaptive: true})
+func (q *Deque[T]) SetShrinkPolicy(policy ShrinkPolicy) {
+	if policy.Delay < 0 {
+		panic(fmt.Errorf("%w: SetShrinkPolicy called with Delay %d", ErrNegativeCount, policy.Delay))
+	}
+	if policy == (ShrinkPolicy{}) {
+		q.shrink = nil
+		return
+	}
+	if policy.FillRatio <= 0 {
+		policy.FillRatio = 0.25
+	}
+	policy.FillRatio = min(policy.FillRatio, 0.5)
+	q.shrink = &shrinkState{policy: policy}
+}
+
+// SetMaxLen sets the maximum number of items that the Deque may hold, and the
+// policy to apply when an item is added to a Deque that already holds maxLen
+// items. The policy is applied by all methods that add items. A maxLen of zero
+// removes the limit. If maxLen is negative, SetMaxLen panics.
+//
+// If the Deque already holds more than maxLen items, then items are evicted
+// from the front until Len() is maxLen, regardless of the policy.
+//
+// Evicted and rejected items are reported to the function set by
+// [Deque.SetEvictFunc].
+//
+// For a Deque created by [NewFixed], a maxLen of zero, or greater than the
+// capacity, sets the maximum length to the capacity.
+func (q *Deque[T]) SetMaxLen(maxLen int, policy OverflowPolicy) {
+	if maxLen < 0 {
+		panic(fmt.Errorf("%w: SetMaxLen(%d) called", ErrNegativeCount, maxLen))
+	}
+	if q.isFixed() && (maxLen == 0 || maxLen > len(q.buf)) {
+		maxLen = len(q.buf)
+	}
+	if maxLen == 0 && q.opts == nil {
+		return
+	}
+	o := q.opt()
+	o.maxLen = maxLen
+	o.overflow = policy
+	if maxLen == 0 || q.count <= maxLen {
+		return
+	}
+	for q.count > maxLen {
+		q.evict(q.popFront())
+	}
+	q.shrinkToFit()
+}
+
+// MaxLen returns the maximum number of items that the Deque may hold, or zero
+// if there is no limit.
+func (q *Deque[T]) MaxLen() int {
+	if q == nil {
+		return 0
+	}
+	return q.maxLen()
+}
+
+// SetEvictFunc sets a function that is called with each item that is evicted
+// or rejected because the Deque reached the maximum length set by
+// [Deque.SetMaxLen]. Setting f to nil stops reporting evicted items.
+func (q *Deque[T]) SetEvictFunc(f func(item T)) {
+	if f == nil && q.opts == nil {
+		return
+	}
+	q.opt().onEvict = f
+}
+
+// SetAllocator sets the allocator that provides the Deque's buffers, and to
+// which buffers are returned when the Deque resizes. Setting a to nil restores
+// the default, which allocates buffers with make and leaves discarded buffers
+// to the garbage collector. The current buffer is returned to a when it is
+// replaced. Use [PoolAllocator] to reuse buffers across all deques of the same
+// item type.
+//
+// A buffer provided to [NewFromBuffer] or [NewFixed] is never given to the
+// allocator.
+func (q *Deque[T]) SetAllocator(a Allocator[T]) {
+	if a == nil && q.opts == nil {
+		return
+	}
+	q.opt().alloc = a
+}
+
 // Swap exchanges the two values at idxA and idxB. It panics if either index is
 // out of range.
 func (q *Deque[T]) Swap(idxA, idxB int) {
 	q.checkRange(idxA)
 	q.checkRange(idxB)
+	q.swap(idxA, idxB)
+}
+
+// TrySwap exchanges the two values at idxA and idxB. If either index is out of
+// range, the queue is not modified and false is returned.
+func (q *Deque[T]) TrySwap(idxA, idxB int) bool {
+	if !q.inRange(idxA) || !q.inRange(idxB) {
+		return false
+	}
+	q.swap(idxA, idxB)
+	return true
+}
+
+func (q *Deque[T]) checkRange(i int) {
+	if uint(i) >= uint(q.count) {
+		panic(&IndexError{Index: i, Len: q.count})
+	}
+}
+
+// opt returns the options of the Deque, which are allocated when the first
+// option is set.
+func (q *Deque[T]) opt() *options[T] {
+	if q.opts == nil {
+		q.opts = new(options[T])
+	}
+	return q.opts
+}
+
+// maxLen returns the maximum length of the Deque, or zero if there is none.
+func (q *Deque[T]) maxLen() int {
+	if q.opts == nil {
+		return 0
+	}
+	return q.opts.maxLen
+}
+
+// isFixed reports whether the Deque was created by NewFixed.
+func (q *Deque[T]) isFixed() bool {
+	return q.opts != nil && q.opts.fixed
+}
+
+// makeRoom applies the overflow policy when elem is to be added to the front,
+// or to the back, of a Deque that holds its maximum number of items. Returns
+// true if elem is to be added.
+func (q *Deque[T]) makeRoom(elem T, front bool) bool {
+	switch q.opts.overflow {
+	case OverflowReject:
+		q.evict(elem)
+		return false
+	case OverflowPanic:
+		panic(fmt.Errorf("%w: maximum length %d reached", ErrFull, q.opts.maxLen))
+	}
+	if front {
+		q.evict(q.popBack())
+	} else {
+		q.evict(q.popFront())
+	}
+	return true
+}
+
+// admit applies the overflow policy to items that are to be added, in order,
+// to the back of the Deque, or to the front if front is true, when the Deque
+// holds n items. Items evicted to make room are removed from the opposite end
+// of the Deque. Returns the items to add.
+func (q *Deque[T]) admit(items []T, n int, front bool) []T {
+	excess := n + len(items) - q.opts.maxLen
+	if excess <= 0 {
+		return items
+	}
+	switch q.opts.overflow {
+	case OverflowReject:
+		room := q.opts.maxLen - n
+		if front {
+			q.evictAll(items[:len(items)-room])
+			return items[len(items)-room:]
+		}
+		q.evictAll(items[room:])
+		return items[:room]
+	case OverflowPanic:
+		panic(fmt.Errorf("%w: maximum length %d exceeded", ErrFull, q.opts.maxLen))
+	}
+	for range min(excess, n) {
+		if front {
+			q.evict(q.popBack())
+		} else {
+			q.evict(q.popFront())
+		}
+	}
+	if len(items) <= q.opts.maxLen {
+		return items
+	}
+	if front {
+		q.evictAll(items[q.opts.maxLen:])
+		return items[:q.opts.maxLen]
+	}
+	q.evictAll(items[:len(items)-q.opts.maxLen])
+	return items[len(items)-q.opts.maxLen:]
+}
+
+// limitCommit applies the overflow policy after k items were committed to the
+// front, or to the back, of the Deque, and made it exceed its maximum length.
+func (q *Deque[T]) limitCommit(k int, front bool) {
+	excess := q.count - q.opts.maxLen
+	mask := len(q.buf) - 1
+	switch q.opts.overflow {
+	case OverflowReject:
+		// Reject the items that would have been added last.
+		var start int
+		if front {
+			start = q.head
+			q.head = (q.head + excess) & mask
+		} else {
+			q.tail = (q.tail - excess) & mask
+			start = q.tail
+		}
+		for i := range excess {
+			q.evict(q.buf[(start+i)&mask])
+		}
+		q.clearBuf(start, excess)
+		q.count -= excess
+		return
+	case OverflowPanic:
+		// Undo the commit.
+		if front {
+			q.clearBuf(q.head, k)
+			q.head = (q.head + k) & mask
+		} else {
+			q.tail = (q.tail - k) & mask
+			q.clearBuf(q.tail, k)
+		}
+		q.count -= k
+		panic(fmt.Errorf("%w: maximum length %d exceeded", ErrFull, q.opts.maxLen))
+	}
+	for range excess {
+		if front {
+			q.evict(q.popBack())
+		} else {
+			q.evict(q.popFront())
+		}
+	}
+}
+
+// copyLimited implements Copy when src holds more items than the maximum
+// length of the Deque.
+func (q *Deque[T]) copyLimited(src Deque[T]) int {
+	if q.opts.overflow == OverflowPanic {
+		panic(fmt.Errorf("%w: maximum length %d exceeded", ErrFull, q.opts.maxLen))
+	}
+	q.Clear()
+	q.Grow(q.opts.maxLen)
+	// Keep the last items, as if pushed one at a time, unless new items are
+	// rejected, in which case keep the first items.
+	first := src.Len() - q.opts.maxLen
+	if q.opts.overflow == OverflowReject {
+		first = 0
+	}
+	for i := range src.Len() {
+		if i < first || i >= first+q.opts.maxLen {
+			q.evict(src.At(i))
+			continue
+		}
+		q.buf[q.tail] = src.At(i)
+		q.tail = q.next(q.tail)
+		q.count++
+	}
+	q.ver++
+	return q.count
+}
+
+// evict reports an item that was evicted or rejected due to the maximum
+// length.
+func (q *Deque[T]) evict(item T) {
+	if f := q.opts.onEvict; f != nil {
+		f(item)
+	}
+}
+
+// evictAll reports each of the items as evicted.
+func (q *Deque[T]) evictAll(items []T) {
+	if f := q.opts.onEvict; f != nil {
+		for _, item := range items {
+			f(item)
+		}
+	}
+}
+
+// checkSlice panics if from and to do not describe a valid range of indexes,
+// where 0 <= from <= to <= q.Len().
+func (q *Deque[T]) checkSlice(from, to int) {
+	if from < 0 || from > to {
+		panic(&IndexError{Index: from, Len: q.Len()})
+	}
+	if to > q.Len() {
+		panic(&IndexError{Index: to, Len: q.Len()})
+	}
+}
+
+// reserve records that n positions were reserved at the front, or at the
+// back, of the Deque.
+func (q *Deque[T]) reserve(n int, front bool) {
+	q.opt().reserved = reservation{n: n, ver: q.ver, front: front, ok: true}
+}
+
+// checkCommit panics if k items cannot be committed to the front, or to the
+// back, of the Deque, because fewer positions were reserved there since the
+// Deque was last modified.
+func (q *Deque[T]) checkCommit(k int, front bool) {
+	var r reservation
+	if q.opts != nil {
+		r = q.opts.reserved
+	}
+	if !r.ok || r.front != front || r.ver != q.ver {
+		if front {
+			panic(fmt.Errorf("%w: CommitFront(%d) called without ReserveFront", ErrOutOfRange, k))
+		}
+		panic(fmt.Errorf("%w: Commit(%d) called without Reserve", ErrOutOfRange, k))
+	}
+	if k < 0 || k > r.n {
+		panic(fmt.Errorf("%w: cannot commit %d items with %d reserved positions", ErrOutOfRange, k, r.n))
+	}
+}
+
+// inRange reports whether i is a valid index into the deque. It is safe to
+// call on a nil deque.
+func (q *Deque[T]) inRange(i int) bool {
+	return q != nil && uint(i) < uint(q.count)
+}
+
+// popFront removes and returns the front element without checking for an
+// empty deque or shrinking the buffer.
+func (q *Deque[T]) popFront() T {
+	if q.old != nil {
+		// Moves the front item to q.buf, if not already moved.
+		q.migrate()
+	}
+	ret := q.buf[q.head]
+	var zero T
+	q.buf[q.head] = zero
+	// Calculate new head position.
+	q.head = q.next(q.head)
+	q.count--
+	q.ver++
+	return ret
+}
+
+// popBack removes and returns the back element without checking for an empty
+// deque or shrinking the buffer.
+func (q *Deque[T]) popBack() T {
+	// Calculate new tail position
+	q.tail = q.prev(q.tail)
+
+	if q.old != nil {
+		// Moves the back item to q.buf, if not already moved.
+		q.migrate()
+	}
+
+	// Remove value at tail.
+	ret := q.buf[q.tail]
+	var zero T
+	q.buf[q.tail] = zero
+	q.count--
+	q.ver++
+	return ret
+}
+
+// remove removes and returns the element at index at, which must be in range.
+// The items on the shorter side of the removed item are moved to close the
+// gap.
+func (q *Deque[T]) remove(at int) T {
+	q.settle()
+	rm := (q.head + at) & (len(q.buf) - 1)
+	ret := q.buf[rm]
+	var zero T
+	if at*2 < q.count {
+		// Move the items before the removed item toward the back.
+		q.ringMove(q.next(q.head), q.head, at)
+		q.buf[q.head] = zero
+		q.head = q.next(q.head)
+	} else {
+		// Move the items after the removed item toward the front.
+		q.ringMove(rm, q.next(rm), q.count-at-1)
+		q.tail = q.prev(q.tail)
+		q.buf[q.tail] = zero
+	}
+	q.count--
+	q.ver++
+	q.shrinkIfExcess()
+	return ret
+}
+
+// retain removes the items for which keep returns false, moving each kept
+// item toward the front to close the gaps, and returns the number of items
+// removed.
+func (q *Deque[T]) retain(keep func(*T) bool) int {
+	if q.Len() == 0 {
+		return 0
+	}
+	q.settle()
+	ver := q.ver
+	mask := len(q.buf) - 1
+	w := q.head
+	r := q.head
+	var kept int
+	for range q.count {
+		ok := keep(&q.buf[r])
+		// Check before moving the item, since the buffer may have changed.
+		if q.ver != ver {
+			panic(ErrModifiedDuringIteration)
+		}
+		if ok {
+			if w != r {
+				q.buf[w] = q.buf[r]
+			}
+			w = (w + 1) & mask // bitwise modulus
+			kept++
+		}
+		r = (r + 1) & mask // bitwise modulus
+	}
+	// keep may have changed the items through their pointers, even if none
+	// were removed.
+	q.ver++
+	removed := q.count - kept
+	if removed == 0 {
+		return 0
+	}
+	q.clearBuf(w, removed)
+	q.tail = w
+	q.count -= removed
+	q.shrinkToFit()
+	return removed
+}
+
+// swap exchanges the values at two in-range indexes.
+func (q *Deque[T]) swap(idxA, idxB int) {
 	if idxA == idxB {
 		return
 	}
+	a := q.slot((q.head + idxA) & (len(q.buf) - 1))
+	b := q.slot((q.head + idxB) & (len(q.buf) - 1))
+	*a, *b = *b, *a
+	q.ver++
+}
 
-	realA := (q.head + idxA) & (len(q.buf) - 1)
-	realB := (q.head + idxB) & (len(q.buf) - 1)
-	q.buf[realA], q.buf[realB] = q.buf[realB], q.buf[realA]
+// splice replaces the del elements at index at with items. The shorter of the
+// parts of the deque before and after the replaced range is moved to make room
+// for, or close the gap left by, the change in size.
+func (q *Deque[T]) splice(at, del int, items []T) {
+	q.settle()
+	q.ver++
+	if q.maxLen() != 0 && len(items) > del {
+		var dropped int
+		items, dropped = q.admitSplice(at, del, items)
+		at -= dropped
+	}
+	delta := len(items) - del
+	if delta > 0 {
+		q.Grow(delta)
+	}
+	if delta != 0 {
+		mask := len(q.buf) - 1
+		if after := q.count - at - del; at <= after {
+			// Move the front part.
+			newHead := (q.head - delta) & mask
+			q.ringMove(newHead, q.head, at)
+			if delta < 0 {
+				q.clearBuf(q.head, -delta)
+			}
+			q.head = newHead
+		} else {
+			// Move the back part.
+			q.ringMove((q.head+at+len(items))&mask, (q.head+at+del)&mask, after)
+			newTail := (q.tail + delta) & mask
+			if delta < 0 {
+				q.clearBuf(newTail, -delta)
+			}
+			q.tail = newTail
+		}
+		q.count += delta
+	}
+	if len(items) != 0 {
+		q.copyToBuf((q.head+at)&(len(q.buf)-1), items)
+	}
+	if delta < 0 {
+		q.shrinkToFit()
+	}
 }
 
-func (q *Deque[T]) checkRange(i int) {
-	if i < 0 || i >= q.count {
-		panic(fmt.Sprintf("deque: index out of range %d with length %d", i, q.Len()))
+// admitSplice applies the overflow policy to a splice that adds more items
+// than it removes. When items are dropped, the front items of the deque, as it
+// would be after the splice, are dropped. Returns the items to insert, and the
+// number of items dropped from the front of the deque before index at.
+func (q *Deque[T]) admitSplice(at, del int, items []T) ([]T, int) {
+	excess := q.count - del + len(items) - q.opts.maxLen
+	if excess <= 0 {
+		return items, 0
+	}
+	switch q.opts.overflow {
+	case OverflowReject:
+		room := len(items) - excess
+		q.evictAll(items[room:])
+		return items[:room], 0
+	case OverflowPanic:
+		panic(fmt.Errorf("%w: maximum length %d exceeded", ErrFull, q.opts.maxLen))
+	}
+	dropped := min(excess, at)
+	for range dropped {
+		q.evict(q.popFront())
+	}
+	// Any remaining excess is made up of the first items to insert, since
+	// the deque holds no more than maxLen items.
+	n := excess - dropped
+	q.evictAll(items[:n])
+	return items[n:], dropped
+}
+
+// ringMove moves n items from buffer position src to buffer position dst,
+// wrapping around the end of the buffer. The source and destination ranges
+// may overlap. Items are moved using block copies.
+func (q *Deque[T]) ringMove(dst, src, n int) {
+	size := len(q.buf)
+	mask := size - 1
+	if n == 0 || dst == src {
+		return
+	}
+	if d := (dst - src) & mask; n+d <= size {
+		// Moving toward the back. Copy from the end of the range, so that
+		// items are not overwritten before they are moved.
+		srcEnd := (src + n) & mask
+		dstEnd := (dst + n) & mask
+		for n != 0 {
+			if srcEnd == 0 {
+				srcEnd = size
+			}
+			if dstEnd == 0 {
+				dstEnd = size
+			}
+			k := min(n, srcEnd, dstEnd, maxBackMove)
+			copy(q.buf[dstEnd-k:dstEnd], q.buf[srcEnd-k:srcEnd])
+			srcEnd -= k
+			dstEnd -= k
+			n -= k
+		}
+		return
+	}
+	// Moving toward the front. Copy from the start of the range.
+	for n != 0 {
+		k := min(n, size-src, size-dst)
+		copy(q.buf[dst:dst+k], q.buf[src:src+k])
+		src = (src + k) & mask
+		dst = (dst + k) & mask
+		n -= k
+	}
+}
+
+// asSlices returns the one or two contiguous regions of the buffer that hold
+// the items of the deque, in order.
+func (q *Deque[T]) asSlices() (front, back []T) {
+	if q.Len() == 0 {
+		return nil, nil
+	}
+	return q.bufSlices(q.head, q.count)
+}
+
+// bufSlices returns the one or two contiguous regions of the buffer that make
+// up the n buffer positions starting at pos, wrapping around the end of the
+// buffer. The capacity of each slice is limited to its length, so that
+// appending to either does not overwrite the buffer.
+func (q *Deque[T]) bufSlices(pos, n int) (a, b []T) {
+	if end := pos + n; end <= len(q.buf) {
+		return q.buf[pos:end:end], nil
+	}
+	end := pos + n - len(q.buf)
+	return q.buf[pos:], q.buf[:end:end]
+}
+
+// copyToBuf copies items into the buffer starting at buffer position pos,
+// wrapping around the end of the buffer. The buffer must have space for the
+// items.
+func (q *Deque[T]) copyToBuf(pos int, items []T) {
+	n := copy(q.buf[pos:], items)
+	copy(q.buf, items[n:])
+}
+
+// copyFromBuf fills dst with items from the buffer starting at buffer
+// position pos, wrapping around the end of the buffer.
+func (q *Deque[T]) copyFromBuf(dst []T, pos int) {
+	if q.old != nil {
+		// Read the items that are not yet moved from the old buffer.
+		for i := range dst {
+			dst[i] = *q.slot((pos + i) & (len(q.buf) - 1))
+		}
+		return
 	}
+	n := copy(dst, q.buf[pos:])
+	copy(dst[n:], q.buf)
+}
+
+// clearBuf zeros n buffer positions starting at buffer position pos, wrapping
+// around the end of the buffer, so that removed items can be garbage
+// collected.
+func (q *Deque[T]) clearBuf(pos, n int) {
+	if end := pos + n; end <= len(q.buf) {
+		clear(q.buf[pos:end])
+		return
+	}
+	clear(q.buf[pos:])
+	clear(q.buf[:pos+n-len(q.buf)])
 }
 
 // prev returns the previous buffer position wrapping around buffer.
@@ -614,44 +2115,124 @@ func (q *Deque[T]) growIfFull() {
 		return
 	}
 	if len(q.buf) == 0 {
-		if q.minCap == 0 {
-			q.minCap = minCapacity
-		}
-		q.buf = make([]T, q.minCap)
+		q.buf = q.firstBuf(1)
 		return
 	}
-	q.resize(q.count << 1)
+	q.autoResize(q.count << 1)
+}
+
+// firstBuf returns the first buffer of a Deque that does not have one, with
+// room for at least n items. Unless a base capacity is set, this is the buffer
+// provided by the caller in the options, if it is large enough.
+func (q *Deque[T]) firstBuf(n int) []T {
+	if q.minCap == 0 {
+		// The default base capacity also keeps the Deque from shrinking the
+		// provided buffer, or shrinking below the default once it has moved
+		// to a larger buffer.
+		q.minCap = minCapacity
+		if o := q.opts; o != nil && o.ext != nil && n <= len(o.ext) {
+			return o.ext
+		}
+	}
+	c := q.minCap
+	for c < n {
+		c <<= 1
+	}
+	return q.newBuf(c)
 }
 
 // shrinkIfExcess resize down if the buffer 1/4 full.
 func (q *Deque[T]) shrinkIfExcess() {
+	if q.shrink != nil {
+		q.applyShrinkPolicy(false)
+		return
+	}
 	if len(q.buf) > q.minCap && (q.count<<2) == len(q.buf) {
-		q.resize(q.count << 1)
+		q.autoResize(q.count << 1)
 	}
 }
 
+// shrinkToFit resizes down to fit the items, after items are removed in bulk,
+// if the buffer is at most 1/4 full.
 func (q *Deque[T]) shrinkToFit() {
+	if q.shrink != nil {
+		q.applyShrinkPolicy(true)
+		return
+	}
 	if len(q.buf) > q.minCap && (q.count<<2) <= len(q.buf) {
-		if q.count == 0 {
-			q.head = 0
-			q.tail = 0
-			q.buf = make([]T, q.minCap)
-			return
-		}
+		q.shrinkTo(q.fitCap(q.count))
+	}
+}
 
-		c := q.minCap
-		for c < q.count {
-			c <<= 1
+// applyShrinkPolicy resizes down, if the shrink policy allows, after one item
+// is removed or, if bulk is true, after any number of items are removed.
+func (q *Deque[T]) applyShrinkPolicy(bulk bool) {
+	s := q.shrink
+	p := &s.policy
+	if p.Never || len(q.buf) <= q.minCap {
+		return
+	}
+	if p.Adaptive && !bulk {
+		// The length before the removal is the largest since the last removal.
+		if q.count >= s.peak {
+			s.peak = q.count + 1
+			s.since = 0
+		} else if s.since++; s.since >= len(q.buf) {
+			s.peak >>= 1
+			s.since = 0
 		}
-		q.resize(c)
 	}
+	if float64(q.count) > p.FillRatio*float64(len(q.buf)) {
+		s.low = 0
+		return
+	}
+	s.low++
+	if s.low < p.Delay {
+		return
+	}
+	// After a single removal, leave room for the length to double, as the
+	// default policy does.
+	need := q.count
+	if !bulk {
+		need <<= 1
+	}
+	if p.Adaptive {
+		need = max(need, s.peak)
+	}
+	if c := q.fitCap(need); c < len(q.buf) {
+		s.low = 0
+		q.shrinkTo(c)
+	}
+}
+
+// fitCap returns the smallest capacity that holds n items and is at least the
+// base capacity.
+func (q *Deque[T]) fitCap(n int) int {
+	c := q.minCap
+	for c < n {
+		c <<= 1
+	}
+	return c
+}
+
+// shrinkTo resizes the buffer down to newSize positions.
+func (q *Deque[T]) shrinkTo(newSize int) {
+	if q.count == 0 {
+		q.head = 0
+		q.tail = 0
+		q.freeBuf(q.buf)
+		q.buf = q.newBuf(newSize)
+		return
+	}
+	q.autoResize(newSize)
 }
 
 // resize resizes the deque to fit exactly twice its current contents. This is
 // used to grow the queue when it is full, and also to shrink it when it is
 // only a quarter full.
 func (q *Deque[T]) resize(newSize int) {
-	newBuf := make([]T, newSize)
+	q.settle()
+	newBuf := q.newBuf(newSize)
 	if q.tail > q.head {
 		copy(newBuf, q.buf[q.head:q.tail])
 	} else {
@@ -661,5 +2242,110 @@ func (q *Deque[T]) resize(newSize int) {
 
 	q.head = 0
 	q.tail = q.count & (newSize - 1) // bitwise modulus, in case buffer is exactly full
+	q.freeBuf(q.buf)
 	q.buf = newBuf
+	q.ver++
+}
+
+// autoResize resizes the buffer when it is full or has excess capacity. With
+// incremental resizing enabled, the items are moved to the new buffer by the
+// pushes and pops that follow.
+func (q *Deque[T]) autoResize(newSize int) {
+	if q.opts == nil || !q.opts.incremental {
+		q.resize(newSize)
+		return
+	}
+	q.settle()
+	// Items keep their order, starting at position 0 of the new buffer.
+	r := q.opts.resize
+	r.buf = q.buf
+	r.head = q.head
+	r.mask = len(q.buf) - 1
+	r.lo = 0
+	r.hi = q.count
+	q.old = r
+	q.buf = q.newBuf(newSize)
+	q.head = 0
+	q.tail = q.count & (newSize - 1) // bitwise modulus
+	q.ver++
+}
+
+// migrate moves the first and the last of the items that are still in the old
+// buffer to the new buffer, and releases the old buffer once all items have
+// been moved. Moving two items for each push or pop ensures that all items are
+// moved before the next resize is needed.
+func (q *Deque[T]) migrate() {
+	r := q.old
+	var zero T
+	old := &r.buf[(r.head+r.lo)&r.mask]
+	q.buf[r.lo] = *old
+	*old = zero
+	r.lo++
+	if r.lo != r.hi {
+		r.hi--
+		old = &r.buf[(r.head+r.hi)&r.mask]
+		q.buf[r.hi] = *old
+		*old = zero
+	}
+	if r.lo == r.hi {
+		q.endResize()
+	}
+}
+
+// settle completes any incremental resize that is in progress, so that all
+// items are in q.buf.
+func (q *Deque[T]) settle() {
+	r := q.old
+	if r == nil {
+		return
+	}
+	for r.lo < r.hi {
+		r.lo += copy(q.buf[r.lo:r.hi], r.buf[(r.head+r.lo)&r.mask:])
+	}
+	q.endResize()
+}
+
+// endResize releases the old buffer of an incremental resize, if one is in
+// progress.
+func (q *Deque[T]) endResize() {
+	if r := q.old; r != nil {
+		q.freeBuf(r.buf)
+		r.buf = nil
+		q.old = nil
+	}
+}
+
+// newBuf returns a zeroed buffer for n items, from the allocator if one is set.
+func (q *Deque[T]) newBuf(n int) []T {
+	if q.opts != nil && q.opts.alloc != nil {
+		return q.opts.alloc.Alloc(n)
+	}
+	return make([]T, n)
+}
+
+// freeBuf returns a buffer that is no longer used to the allocator. A buffer
+// that was provided by the caller is cleared instead, so that it does not keep
+// the items that were copied out of it reachable.
+func (q *Deque[T]) freeBuf(buf []T) {
+	o := q.opts
+	if o == nil || len(buf) == 0 {
+		return
+	}
+	if len(o.ext) != 0 && &buf[0] == &o.ext[0] {
+		clear(buf)
+		return
+	}
+	if o.alloc != nil {
+		o.alloc.Free(buf)
+	}
+}
+
+// slot returns a pointer to the storage for the item at buffer position p,
+// which is in the old buffer if the item has not yet been moved by an
+// incremental resize.
+func (q *Deque[T]) slot(p int) *T {
+	if r := q.old; r != nil && p >= r.lo && p < r.hi {
+		return &r.buf[(r.head+p)&r.mask]
+	}
+	return &q.buf[p]
 }
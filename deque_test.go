@@ -1,7 +1,9 @@
 package deque
 
 import (
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"slices"
 	"testing"
 	"unicode"
@@ -375,6 +377,40 @@ func TestRotate(t *testing.T) {
 	}
 }
 
+func TestRotateRandom(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	for _, size := range []int{2, 5, 15, 16, 17, 40, 64, 100} {
+		var q Deque[int]
+		for i := range size {
+			q.PushBack(i + 1)
+		}
+		model := q.AppendToSlice(nil)
+		for range 200 {
+			// Randomly move the head to exercise wrapping.
+			if rng.IntN(2) == 0 {
+				q.PushFront(q.PopBack())
+				model = append(model[len(model)-1:], model[:len(model)-1]...)
+			}
+			n := rng.IntN(4*size) - 2*size
+			q.Rotate(n)
+			k := ((n % size) + size) % size
+			model = append(model[k:], model[:k]...)
+			if !slices.Equal(q.AppendToSlice(nil), model) {
+				t.Fatalf("size %d: wrong contents after Rotate(%d)", size, n)
+			}
+			var nonZero int
+			for i := range q.buf {
+				if q.buf[i] != 0 {
+					nonZero++
+				}
+			}
+			if nonZero != size {
+				t.Fatalf("size %d: buffer has moved-from items after Rotate(%d)", size, n)
+			}
+		}
+	}
+}
+
 func TestAt(t *testing.T) {
 	var q Deque[int]
 
@@ -650,6 +686,350 @@ func TestRemove(t *testing.T) {
 	}
 }
 
+func TestInsertRemoveRandom(t *testing.T) {
+	rng := rand.New(rand.NewPCG(5, 6))
+	var q Deque[int]
+	var model []int
+	// Grow past maxBackMove so that moves are done in more than one block.
+	for i := range 3000 {
+		at := rng.IntN(len(model) + 1)
+		q.Insert(at, i+1)
+		model = slices.Insert(model, at, i+1)
+		if rng.IntN(3) == 0 {
+			at = rng.IntN(len(model))
+			if q.Remove(at) != model[at] {
+				t.Fatalf("wrong item removed from index %d", at)
+			}
+			model = slices.Delete(model, at, at+1)
+		}
+	}
+	if !slices.Equal(q.AppendToSlice(nil), model) {
+		t.Fatal("wrong contents after inserts")
+	}
+	for len(model) != 0 {
+		at := rng.IntN(len(model))
+		if q.Remove(at) != model[at] {
+			t.Fatalf("wrong item removed from index %d", at)
+		}
+		model = slices.Delete(model, at, at+1)
+		if len(model)%100 == 0 && !slices.Equal(q.AppendToSlice(nil), model) {
+			t.Fatalf("wrong contents with %d items", len(model))
+		}
+	}
+	for i := range q.buf {
+		if q.buf[i] != 0 {
+			t.Fatal("buffer has removed items")
+		}
+	}
+}
+
+func TestInsertSlice(t *testing.T) {
+	q := new(Deque[rune])
+	for _, x := range "ABCDEFG" {
+		q.PushBack(x)
+	}
+	q.InsertSlice(5, 'x', 'y') // ABCDExyFG
+	q.InsertSlice(1, 'z')      // AzBCDExyFG
+	q.InsertSlice(-1, '<')     // <AzBCDExyFG
+	q.InsertSlice(99, '>')     // <AzBCDExyFG>
+	q.InsertSlice(3)
+	if s := string(q.AppendToSlice(nil)); s != "<AzBCDExyFG>" {
+		t.Fatal("wrong contents:", s)
+	}
+
+	// Insert more items than the deque holds, near each end.
+	var qi Deque[int]
+	qi.PushBackSlice([]int{0, 1, 2, 3})
+	items := make([]int, 40)
+	for i := range items {
+		items[i] = 100 + i
+	}
+	qi.InsertSlice(1, items...)
+	qi.InsertSlice(qi.Len()-1, items...)
+	want := slices.Concat([]int{0}, items, []int{1, 2}, items, []int{3})
+	if !slices.Equal(qi.AppendToSlice(nil), want) {
+		t.Fatal("wrong contents:", qi.AppendToSlice(nil))
+	}
+}
+
+func TestRemoveRange(t *testing.T) {
+	q := new(Deque[rune])
+	for _, x := range "ABCDEFGHIJ" {
+		q.PushBack(x)
+	}
+	q.RemoveRange(7, 9) // ABCDEFGJ
+	q.RemoveRange(1, 3) // ADEFGJ
+	q.RemoveRange(2, 2)
+	if s := string(q.AppendToSlice(nil)); s != "ADEFGJ" {
+		t.Fatal("wrong contents:", s)
+	}
+	q.RemoveRange(0, q.Len())
+	if q.Len() != 0 {
+		t.Fatal("expected empty deque")
+	}
+
+	var qi Deque[int]
+	for i := range 200 {
+		qi.PushBack(i)
+	}
+	qi.RemoveRange(10, 190)
+	if qi.Cap() != 32 {
+		t.Fatal("expected buffer to shrink to 32, got", qi.Cap())
+	}
+	for i := range qi.buf {
+		x := qi.buf[i]
+		if x != 0 && (x > 9 && x < 190) {
+			t.Fatal("queue has non-zero removed elements")
+		}
+	}
+
+	for _, r := range [][2]int{{-1, 2}, {2, 1}, {0, 21}} {
+		err := recoverError(func() { qi.RemoveRange(r[0], r[1]) })
+		if !errors.Is(err, ErrOutOfRange) {
+			t.Error("expected ErrOutOfRange for range", r, "got", err)
+		}
+	}
+}
+
+func TestSplice(t *testing.T) {
+	q := new(Deque[rune])
+	for _, x := range "ABCDEFG" {
+		q.PushBack(x)
+	}
+	removed := q.Splice(1, 3, 'x', 'y')
+	if string(removed) != "BCD" {
+		t.Fatal("wrong removed items:", string(removed))
+	}
+	if s := string(q.AppendToSlice(nil)); s != "AxyEFG" {
+		t.Fatal("wrong contents:", s)
+	}
+	if removed = q.Splice(6, 0, 'z'); removed != nil {
+		t.Fatal("expected nil removed items")
+	}
+	if s := string(q.AppendToSlice(nil)); s != "AxyEFGz" {
+		t.Fatal("wrong contents:", s)
+	}
+	err := recoverError(func() { q.Splice(1, -1) })
+	if !errors.Is(err, ErrNegativeCount) {
+		t.Fatal("expected ErrNegativeCount, got", err)
+	}
+	err = recoverError(func() { q.Splice(5, 3) })
+	if !errors.Is(err, ErrOutOfRange) {
+		t.Fatal("expected ErrOutOfRange, got", err)
+	}
+}
+
+func TestSpliceRandom(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	var q Deque[int]
+	var model []int
+	var next int
+
+	for range 5000 {
+		// Randomly move the head to exercise wrapping.
+		if rng.IntN(4) == 0 && q.Len() != 0 {
+			q.PushBack(q.PopFront())
+			model = append(model[1:], model[0])
+		}
+		at := rng.IntN(len(model) + 1)
+		del := rng.IntN(len(model) - at + 1)
+		if rng.IntN(3) == 0 {
+			del = 0
+		}
+		items := make([]int, rng.IntN(8))
+		for i := range items {
+			items[i] = next
+			next++
+		}
+		if len(model) > 300 {
+			items = nil
+		}
+
+		removed := q.Splice(at, del, items...)
+		if !slices.Equal(removed, model[at:at+del]) {
+			t.Fatal("wrong removed items")
+		}
+		model = slices.Replace(model, at, at+del, items...)
+		if !slices.Equal(q.AppendToSlice(nil), model) {
+			t.Fatal("wrong contents after Splice")
+		}
+	}
+
+	// Check that there are no remaining references to removed items.
+	live := make(map[int]bool)
+	for _, x := range model {
+		live[x] = true
+	}
+	for i := range q.buf {
+		if x := q.buf[i]; x != 0 && !live[x] {
+			t.Fatal("queue has non-zero removed element", x)
+		}
+	}
+}
+
+func TestSpliceMaxLen(t *testing.T) {
+	var q Deque[int]
+	var evicted []int
+	q.SetEvictFunc(func(item int) {
+		evicted = append(evicted, item)
+	})
+	q.PushBackSlice([]int{0, 1, 2, 3, 4})
+	q.SetMaxLen(6, OverflowDrop)
+
+	q.InsertSlice(2, 10, 11)
+	if !slices.Equal(q.AppendToSlice(nil), []int{1, 10, 11, 2, 3, 4}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+	if !slices.Equal(evicted, []int{0}) {
+		t.Fatal("wrong evicted items:", evicted)
+	}
+
+	evicted = evicted[:0]
+	removed := q.Splice(1, 1, 20, 21, 22, 23)
+	if !slices.Equal(removed, []int{10}) {
+		t.Fatal("wrong removed items:", removed)
+	}
+	if !slices.Equal(q.AppendToSlice(nil), []int{22, 23, 11, 2, 3, 4}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+	if !slices.Equal(evicted, []int{1, 20, 21}) {
+		t.Fatal("wrong evicted items:", evicted)
+	}
+
+	q.SetMaxLen(6, OverflowReject)
+	evicted = evicted[:0]
+	q.Splice(0, 1, 30, 31)
+	if !slices.Equal(q.AppendToSlice(nil), []int{30, 23, 11, 2, 3, 4}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+	if !slices.Equal(evicted, []int{31}) {
+		t.Fatal("wrong rejected items:", evicted)
+	}
+
+	q.SetMaxLen(6, OverflowPanic)
+	err := recoverError(func() { q.InsertSlice(3, 1) })
+	if !errors.Is(err, ErrFull) {
+		t.Fatal("expected ErrFull, got", err)
+	}
+	q.Splice(0, 2, 1, 2)
+	if !slices.Equal(q.AppendToSlice(nil), []int{1, 2, 11, 2, 3, 4}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+}
+
+func TestDeleteFunc(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	var q Deque[int]
+	var model []int
+	for round := range 2000 {
+		// Move the head to exercise wrapping.
+		for range rng.IntN(q.Len() + 1) {
+			q.PushBack(q.PopFront())
+		}
+		model = q.AppendToSlice(model[:0])
+		for range rng.IntN(100) {
+			x := rng.IntN(1000) + 1
+			q.PushBack(x)
+			model = append(model, x)
+		}
+		m := rng.IntN(5) + 1
+		del := func(x int) bool { return x%m == 0 }
+		want := len(model)
+		model = slices.DeleteFunc(model, del)
+		want -= len(model)
+		var n int
+		switch round % 3 {
+		case 0:
+			n = q.DeleteFunc(del)
+		case 1:
+			n = q.Retain(func(x int) bool { return !del(x) })
+		default:
+			n = q.RetainMut(func(x *int) bool { return !del(*x) })
+		}
+		if n != want {
+			t.Fatalf("round %d: removed %d items, expected %d", round, n, want)
+		}
+		if !slices.Equal(q.AppendToSlice(nil), model) {
+			t.Fatalf("round %d: wrong contents", round)
+		}
+		// Check that there are no remaining references to removed items.
+		var nonZero int
+		for _, x := range q.buf {
+			if x != 0 {
+				nonZero++
+			}
+		}
+		if nonZero != q.Len() {
+			t.Fatalf("round %d: queue has non-zero removed items", round)
+		}
+	}
+}
+
+func TestRetainMut(t *testing.T) {
+	var a countingAllocator[int]
+	var q Deque[int]
+	q.SetAllocator(&a)
+	for i := range 300 {
+		q.PushFront(i)
+	}
+	if q.RetainMut(func(x *int) bool { return true }) != 0 {
+		t.Fatal("expected no items removed")
+	}
+	freed := a.freed
+	n := q.RetainMut(func(x *int) bool {
+		*x *= 2
+		return *x < 20
+	})
+	if n != 290 {
+		t.Fatal("removed", n, "items, expected 290")
+	}
+	if !slices.Equal(q.AppendToSlice(nil), []int{18, 16, 14, 12, 10, 8, 6, 4, 2, 0}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+	// The buffer is resized once to fit the remaining items.
+	if q.Cap() != minCapacity || len(a.live) != 1 || a.freed != freed+1 {
+		t.Fatal("expected buffer to be resized once, capacity", q.Cap())
+	}
+	if q.DeleteFunc(func(int) bool { return true }) != 10 || q.Len() != 0 {
+		t.Fatal("expected all items removed")
+	}
+
+	q.PushBackSlice([]int{1, 2, 3})
+	err := recoverError(func() {
+		q.DeleteFunc(func(x int) bool {
+			q.Set(2, 0)
+			return false
+		})
+	})
+	if !errors.Is(err, ErrModifiedDuringIteration) {
+		t.Fatal("expected ErrModifiedDuringIteration, got", err)
+	}
+}
+
+func TestDeleteFuncModified(t *testing.T) {
+	var q Deque[int]
+	for i := range 10 {
+		q.PushBack(i)
+	}
+	// When item 5 is reached, the odd items before it have been moved to
+	// indexes 0 and 1, and item 5 would be moved to index 2. The change made
+	// by the function must not be overwritten by that move.
+	err := recoverError(func() {
+		q.DeleteFunc(func(x int) bool {
+			if x == 5 {
+				q.Set(2, -2)
+			}
+			return x%2 == 0
+		})
+	})
+	if !errors.Is(err, ErrModifiedDuringIteration) {
+		t.Fatal("expected ErrModifiedDuringIteration, got", err)
+	}
+	if q.Len() != 10 || q.At(2) != -2 {
+		t.Fatal("deque was changed after modification was detected:", q.AppendToSlice(nil))
+	}
+}
+
 func TestSwap(t *testing.T) {
 	var q Deque[string]
 
@@ -762,6 +1142,291 @@ func TestRIter(t *testing.T) {
 	})
 }
 
+func TestAllBackward(t *testing.T) {
+	var q Deque[int]
+	for range q.All() {
+		t.Fatal("iterated when empty")
+	}
+	for range q.Backward() {
+		t.Fatal("iterated when empty")
+	}
+
+	// Wrap the items around the end of the buffer.
+	for i := range 10 {
+		q.PushBack(i)
+		q.PopFront()
+	}
+	for i := range 12 {
+		q.PushBack(i)
+	}
+	n := 0
+	for i, item := range q.All() {
+		if i != n || item != i {
+			t.Fatalf("All yielded %d at %d, expected index %d", item, i, n)
+		}
+		n++
+	}
+	if n != q.Len() {
+		t.Fatal("All did not yield all items")
+	}
+	for i, item := range q.Backward() {
+		n--
+		if i != n || item != i {
+			t.Fatalf("Backward yielded %d at %d, expected index %d", item, i, n)
+		}
+	}
+	if !slices.Equal(slices.Collect(q.Values()), q.AppendToSlice(nil)) {
+		t.Fatal("wrong items from Values")
+	}
+
+	// All items are yielded while an incremental resize is in progress.
+	q.SetIncrementalResize(true)
+	for i := 12; i < 17; i++ {
+		q.PushBack(i)
+	}
+	if q.old == nil {
+		t.Fatal("expected incremental resize in progress")
+	}
+	for i, item := range q.All() {
+		if item != i {
+			t.Fatalf("index %d contains %d", i, item)
+		}
+	}
+
+	assertPanics(t, "All must panic when deque modified during iteration", func() {
+		for i := range q.All() {
+			if i == 3 {
+				q.PushFront(-1)
+			}
+		}
+	})
+	assertPanics(t, "Backward must panic when deque modified during iteration", func() {
+		for i := range q.Backward() {
+			if i == 3 {
+				q.PopBack()
+			}
+		}
+	})
+}
+
+func TestIterRange(t *testing.T) {
+	var q Deque[int]
+	for i := range 20 {
+		q.PushFront(19 - i)
+	}
+	var got []int
+	for i, item := range q.IterRange(5, 9) {
+		if item != i {
+			t.Fatalf("index %d contains %d", i, item)
+		}
+		got = append(got, i)
+	}
+	if !slices.Equal(got, []int{5, 6, 7, 8}) {
+		t.Fatal("wrong indexes from IterRange:", got)
+	}
+	got = got[:0]
+	for i, item := range q.RIterRange(15, 20) {
+		if item != i {
+			t.Fatalf("index %d contains %d", i, item)
+		}
+		got = append(got, i)
+		if i == 17 {
+			break
+		}
+	}
+	if !slices.Equal(got, []int{19, 18, 17}) {
+		t.Fatal("wrong indexes from RIterRange:", got)
+	}
+	for range q.IterRange(20, 20) {
+		t.Fatal("iterated empty range")
+	}
+	for range q.RIterRange(0, 0) {
+		t.Fatal("iterated empty range")
+	}
+
+	var idxErr *IndexError
+	for _, r := range [][2]int{{-1, 2}, {3, 2}, {0, 21}} {
+		err := recoverError(func() {
+			for range q.IterRange(r[0], r[1]) {
+			}
+		})
+		if !errors.As(err, &idxErr) {
+			t.Fatalf("expected *IndexError for range %v, got %v", r, err)
+		}
+		err = recoverError(func() {
+			for range q.RIterRange(r[0], r[1]) {
+			}
+		})
+		if !errors.As(err, &idxErr) {
+			t.Fatalf("expected *IndexError for range %v, got %v", r, err)
+		}
+	}
+	err := recoverError(func() {
+		for range q.RIterRange(0, 10) {
+			q.PushBack(0)
+		}
+	})
+	if !errors.Is(err, ErrModifiedDuringIteration) {
+		t.Fatal("expected ErrModifiedDuringIteration, got", err)
+	}
+}
+
+func TestIterModified(t *testing.T) {
+	var q Deque[int]
+	for i := range 20 {
+		q.PushBack(i)
+	}
+	// Each of these modifications leaves head and tail where they were.
+	mods := map[string]func(){
+		"Set":  func() { q.Set(10, -1) },
+		"Swap": func() { q.Swap(10, 11) },
+		"PushBack and PopBack": func() {
+			q.PushBack(-1)
+			q.PopBack()
+		},
+		"PushFront and PopFront": func() {
+			q.PushFront(-1)
+			q.PopFront()
+		},
+		"Remove and Insert": func() {
+			x := q.Remove(10)
+			q.Insert(10, x)
+		},
+		"AsMutSlices": func() { q.AsMutSlices() },
+		"RetainMut":   func() { q.RetainMut(func(*int) bool { return true }) },
+		"Rotate": func() {
+			q.Rotate(1)
+			q.Rotate(-1)
+		},
+	}
+	iters := map[string]func(func()){
+		"Iter": func(mod func()) {
+			for x := range q.Iter() {
+				if x == 5 {
+					mod()
+				}
+			}
+		},
+		"RIter": func(mod func()) {
+			for x := range q.RIter() {
+				if x == 15 {
+					mod()
+				}
+			}
+		},
+		"All": func(mod func()) {
+			for i := range q.All() {
+				if i == 5 {
+					mod()
+				}
+			}
+		},
+		"Backward": func(mod func()) {
+			for i := range q.Backward() {
+				if i == 15 {
+					mod()
+				}
+			}
+		},
+		"IterRange": func(mod func()) {
+			for i := range q.IterRange(2, 18) {
+				if i == 5 {
+					mod()
+				}
+			}
+		},
+		"RIterRange": func(mod func()) {
+			for i := range q.RIterRange(2, 18) {
+				if i == 15 {
+					mod()
+				}
+			}
+		},
+	}
+	for iterName, iterate := range iters {
+		for modName, mod := range mods {
+			err := recoverError(func() { iterate(mod) })
+			if !errors.Is(err, ErrModifiedDuringIteration) {
+				t.Errorf("%s with %s: expected ErrModifiedDuringIteration, got %v", iterName, modName, err)
+			}
+		}
+	}
+
+	// Reading and iterating again do not count as modification.
+	for range q.Iter() {
+		q.At(3)
+		q.AsSlices()
+		for range q.All() {
+		}
+	}
+
+	var e ExactDeque[int]
+	var c ChunkedDeque[int]
+	for i := range 20 {
+		e.PushBack(i)
+		c.PushBack(i)
+	}
+	mods = map[string]func(){
+		"ExactDeque Set": func() { e.Set(10, -1) },
+		"ExactDeque PushBack and PopBack": func() {
+			e.PushBack(-1)
+			e.PopBack()
+		},
+		"ExactDeque PopBack and PushBack": func() { e.PushBack(e.PopBack()) },
+		"ChunkedDeque Set":                func() { c.Set(10, -1) },
+		"ChunkedDeque Swap":               func() { c.Swap(10, 11) },
+		"ChunkedDeque PushFront and PopFront": func() {
+			c.PushFront(-1)
+			c.PopFront()
+		},
+		"ChunkedDeque Remove and Insert": func() {
+			x := c.Remove(15)
+			c.Insert(15, x)
+		},
+	}
+	iters = map[string]func(func()){
+		"ExactDeque Iter": func(mod func()) {
+			for x := range e.Iter() {
+				if x == 5 {
+					mod()
+				}
+			}
+		},
+		"ExactDeque RIter": func(mod func()) {
+			for x := range e.RIter() {
+				if x == 15 {
+					mod()
+				}
+			}
+		},
+		"ChunkedDeque Iter": func(mod func()) {
+			for x := range c.Iter() {
+				if x == 5 {
+					mod()
+				}
+			}
+		},
+		"ChunkedDeque RIter": func(mod func()) {
+			for x := range c.RIter() {
+				if x == 15 {
+					mod()
+				}
+			}
+		},
+	}
+	for iterName, iterate := range iters {
+		for modName, mod := range mods {
+			if iterName[:5] != modName[:5] {
+				continue
+			}
+			err := recoverError(func() { iterate(mod) })
+			if !errors.Is(err, ErrModifiedDuringIteration) {
+				t.Errorf("%s with %s: expected ErrModifiedDuringIteration, got %v", iterName, modName, err)
+			}
+		}
+	}
+}
+
 func TestIterPopBack(t *testing.T) {
 	const (
 		baseCap = 32
@@ -945,6 +1610,62 @@ func TestIterPopFront(t *testing.T) {
 	}
 }
 
+func TestPopWhile(t *testing.T) {
+	for _, incremental := range []bool{false, true} {
+		var a countingAllocator[int]
+		var q Deque[int]
+		q.SetAllocator(&a)
+		q.SetIncrementalResize(incremental)
+
+		for range q.PopFrontWhile(func(int) bool { return true }) {
+			t.Fatal("popped item from empty deque")
+		}
+		for i := range 1000 {
+			q.PushBack(i)
+		}
+
+		freed := a.freed
+		var want int
+		for x := range q.PopFrontWhile(func(x int) bool { return x < 900 }) {
+			if x != want {
+				t.Fatalf("popped %d, expected %d", x, want)
+			}
+			want++
+		}
+		if want != 900 || q.Len() != 100 || q.Front() != 900 {
+			t.Fatal("wrong items popped from front")
+		}
+		// The buffer is resized once, when iteration ends.
+		if q.Cap() != 128 || a.freed > freed+1 {
+			t.Fatalf("incremental %v: capacity %d, %d buffers freed", incremental, q.Cap(), a.freed-freed)
+		}
+
+		want = 999
+		for x := range q.PopBackWhile(func(x int) bool { return x >= 950 }) {
+			if x != want {
+				t.Fatalf("popped %d, expected %d", x, want)
+			}
+			want--
+			if x == 990 {
+				break
+			}
+		}
+		if q.Back() != 989 {
+			t.Fatal("expected popping to stop at break, back is", q.Back())
+		}
+		for range q.PopBackWhile(func(x int) bool { return x >= 950 }) {
+		}
+		if q.Back() != 949 || q.Len() != 50 {
+			t.Fatal("wrong items popped from back")
+		}
+		for range q.PopFrontWhile(func(int) bool { return true }) {
+		}
+		if q.Len() != 0 || q.Cap() != minCapacity {
+			t.Fatal("expected empty deque with minimum capacity")
+		}
+	}
+}
+
 func TestFrontBackOutOfRangePanics(t *testing.T) {
 	const msg = "should panic when peeking empty queue"
 	var q Deque[int]
@@ -1091,20 +1812,862 @@ func TestSetMBaseapacity(t *testing.T) {
 	}
 }
 
-func TestCopyInSliceCopyOutSlice(t *testing.T) {
+func TestPushBackSlice(t *testing.T) {
 	var q Deque[int]
+	q.PushBackSlice(nil)
+	if q.Cap() != 0 {
+		t.Fatal("pushing empty slice should not allocate")
+	}
 
-	out := make([]int, 5)
-	n := q.CopyOutSlice(out)
-	if n != 0 {
-		t.Fatal("expected nothing replaced")
+	q.PushBackSlice([]int{0, 1, 2})
+	if !slices.Equal(q.AppendToSlice(nil), []int{0, 1, 2}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
 	}
-	content1 := []int{1, 2, 3}
-	q.CopyInSlice(content1)
-	if q.Front() != 1 {
-		t.Fatal("wrong value for Fromt")
+
+	// Move the head near the end of the buffer so that items wrap.
+	for i := range 12 {
+		q.PushBack(i + 3)
+		q.PopFront()
 	}
-	if q.Back() != 3 {
+	want := q.AppendToSlice(nil)
+	items := make([]int, 10)
+	for i := range items {
+		items[i] = 100 + i
+	}
+	q.PushBackSlice(items)
+	want = append(want, items...)
+	if !slices.Equal(q.AppendToSlice(nil), want) {
+		t.Fatal("wrong contents after wrapped push:", q.AppendToSlice(nil))
+	}
+	if q.Back() != 109 {
+		t.Fatal("wrong value at back")
+	}
+
+	// Grow by more than double.
+	big := make([]int, 1000)
+	for i := range big {
+		big[i] = i
+	}
+	q.PushBackSlice(big)
+	want = append(want, big...)
+	if !slices.Equal(q.AppendToSlice(nil), want) {
+		t.Fatal("wrong contents after large push")
+	}
+	if q.Cap() != 1024 {
+		t.Fatal("expected capacity 1024, got", q.Cap())
+	}
+	q.PushBack(-1)
+	if q.Back() != -1 {
+		t.Fatal("wrong value at back")
+	}
+}
+
+func TestPushFrontSlice(t *testing.T) {
+	var q Deque[int]
+	q.PushFrontSlice([]int{3, 4, 5})
+	q.PushFrontSlice([]int{0, 1, 2})
+	if !slices.Equal(q.AppendToSlice(nil), []int{0, 1, 2, 3, 4, 5}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+
+	want := q.AppendToSlice(nil)
+	items := make([]int, 40)
+	for i := range items {
+		items[i] = 100 + i
+	}
+	q.PushFrontSlice(items)
+	want = append(items, want...)
+	if !slices.Equal(q.AppendToSlice(nil), want) {
+		t.Fatal("wrong contents after large push:", q.AppendToSlice(nil))
+	}
+	if q.Front() != 100 {
+		t.Fatal("wrong value at front")
+	}
+	q.PushFront(-1)
+	if q.Front() != -1 || q.Back() != 5 {
+		t.Fatal("wrong values at ends")
+	}
+}
+
+func TestPushSeq(t *testing.T) {
+	var q Deque[int]
+	q.PushBackSeq(slices.Values([]int{3, 4, 5}))
+	q.PushFrontSeq(slices.Values([]int{0, 1, 2}))
+	if !slices.Equal(q.AppendToSlice(nil), []int{0, 1, 2, 3, 4, 5}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+
+	var other Deque[int]
+	other.PushBackSeq(q.Iter())
+	if !equalInt(q, other) {
+		t.Fatal("different contents after PushBackSeq")
+	}
+
+	// Capacity is reserved once for all the items.
+	var a countingAllocator[int]
+	var r Deque[int]
+	r.SetAllocator(&a)
+	r.PushBackSeq(slices.Values(make([]int, 1000)))
+	if r.Len() != 1000 || len(a.live) != 1 || a.freed != 0 {
+		t.Fatal("expected a single resize, got", len(a.live)+a.freed)
+	}
+}
+
+func TestPushSliceMaxLen(t *testing.T) {
+	var q Deque[int]
+	var evicted []int
+	q.SetEvictFunc(func(item int) {
+		evicted = append(evicted, item)
+	})
+	q.SetMaxLen(4, OverflowDrop)
+
+	q.PushBackSlice([]int{0, 1, 2})
+	q.PushBackSlice([]int{3, 4, 5})
+	if !slices.Equal(q.AppendToSlice(nil), []int{2, 3, 4, 5}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+	if !slices.Equal(evicted, []int{0, 1}) {
+		t.Fatal("wrong evicted items:", evicted)
+	}
+
+	evicted = evicted[:0]
+	q.PushFrontSlice([]int{-2, -1})
+	if !slices.Equal(q.AppendToSlice(nil), []int{-2, -1, 2, 3}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+	if !slices.Equal(evicted, []int{5, 4}) {
+		t.Fatal("wrong evicted items:", evicted)
+	}
+
+	// More items than the maximum length.
+	evicted = evicted[:0]
+	q.PushBackSlice([]int{10, 11, 12, 13, 14, 15})
+	if !slices.Equal(q.AppendToSlice(nil), []int{12, 13, 14, 15}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+	if !slices.Equal(evicted, []int{-2, -1, 2, 3, 10, 11}) {
+		t.Fatal("wrong evicted items:", evicted)
+	}
+	evicted = evicted[:0]
+	q.PushFrontSlice([]int{20, 21, 22, 23, 24})
+	if !slices.Equal(q.AppendToSlice(nil), []int{20, 21, 22, 23}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+	if !slices.Equal(evicted, []int{15, 14, 13, 12, 24}) {
+		t.Fatal("wrong evicted items:", evicted)
+	}
+
+	q.SetMaxLen(5, OverflowReject)
+	evicted = evicted[:0]
+	q.PushBackSlice([]int{30, 31})
+	q.PushFrontSlice([]int{40, 41})
+	if !slices.Equal(q.AppendToSlice(nil), []int{20, 21, 22, 23, 30}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+	if !slices.Equal(evicted, []int{31, 40, 41}) {
+		t.Fatal("wrong rejected items:", evicted)
+	}
+	q.PopFront()
+	q.PushFrontSlice([]int{40, 41})
+	if !slices.Equal(q.AppendToSlice(nil), []int{41, 21, 22, 23, 30}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+
+	q.SetMaxLen(5, OverflowPanic)
+	err := recoverError(func() { q.PushBackSlice([]int{1}) })
+	if !errors.Is(err, ErrFull) {
+		t.Fatal("expected ErrFull, got", err)
+	}
+	q.PushBackSlice(nil)
+}
+
+func TestPopFrontInto(t *testing.T) {
+	var q Deque[int]
+	dst := make([]int, 10)
+	if q.PopFrontInto(dst) != 0 {
+		t.Fatal("expected nothing popped from empty queue")
+	}
+
+	// Start with head near the end of the buffer so that items wrap.
+	for i := range 12 {
+		q.PushBack(i)
+		q.PopFront()
+	}
+	for i := range 14 {
+		q.PushBack(i)
+	}
+	if n := q.PopFrontInto(dst); n != len(dst) {
+		t.Fatal("expected", len(dst), "items popped, got", n)
+	}
+	if !slices.Equal(dst, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}) {
+		t.Fatal("wrong items popped:", dst)
+	}
+	if q.Len() != 4 || q.Front() != 10 {
+		t.Fatal("wrong items remaining")
+	}
+	// Check that there are no remaining references to popped items.
+	for i := range q.buf {
+		if q.buf[i] != 0 && (q.buf[i] < 10 || q.buf[i] > 13) {
+			t.Fatal("queue has non-zero popped elements")
+		}
+	}
+	clear(dst)
+	if n := q.PopFrontInto(dst); n != 4 {
+		t.Fatal("expected 4 items popped, got", n)
+	}
+	if !slices.Equal(dst[:4], []int{10, 11, 12, 13}) {
+		t.Fatal("wrong items popped:", dst[:4])
+	}
+	if q.Len() != 0 {
+		t.Fatal("expected empty queue")
+	}
+}
+
+func TestPopBackInto(t *testing.T) {
+	var q Deque[int]
+	for i := range 12 {
+		q.PushFront(i)
+		q.PopBack()
+	}
+	for i := range 14 {
+		q.PushBack(i)
+	}
+	dst := make([]int, 5)
+	if n := q.PopBackInto(dst); n != len(dst) {
+		t.Fatal("expected", len(dst), "items popped, got", n)
+	}
+	if !slices.Equal(dst, []int{13, 12, 11, 10, 9}) {
+		t.Fatal("wrong items popped:", dst)
+	}
+	if q.Len() != 9 || q.Back() != 8 {
+		t.Fatal("wrong items remaining")
+	}
+	q.PushBack(99)
+	if q.Back() != 99 {
+		t.Fatal("wrong value at back")
+	}
+	if q.PopBackInto(nil) != 0 {
+		t.Fatal("expected nothing popped into empty slice")
+	}
+}
+
+func TestPopIntoShrink(t *testing.T) {
+	const size = minCapacity * 8
+	var q Deque[int]
+	for i := range size {
+		q.PushBack(i)
+	}
+	dst := make([]int, size-1)
+	q.PopFrontInto(dst)
+	if q.Cap() != minCapacity {
+		t.Fatal("expected capacity", minCapacity, "got", q.Cap())
+	}
+	if q.Front() != size-1 {
+		t.Fatal("wrong remaining item")
+	}
+
+	for i := range size {
+		q.PushBack(i)
+	}
+	q.PopBackInto(dst)
+	if q.Cap() != minCapacity {
+		t.Fatal("expected capacity", minCapacity, "got", q.Cap())
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	var q Deque[int]
+	if q.DiscardFront(5) != 0 || q.DiscardBack(5) != 0 {
+		t.Fatal("expected nothing discarded from empty queue")
+	}
+	for i := range 100 {
+		q.PushBack(i)
+	}
+	if q.DiscardFront(10) != 10 {
+		t.Fatal("wrong discard count")
+	}
+	if q.DiscardBack(20) != 20 {
+		t.Fatal("wrong discard count")
+	}
+	if q.Front() != 10 || q.Back() != 79 || q.Len() != 70 {
+		t.Fatal("wrong items remaining")
+	}
+	if q.DiscardFront(0) != 0 {
+		t.Fatal("wrong discard count")
+	}
+	if q.DiscardBack(100) != 70 {
+		t.Fatal("wrong discard count")
+	}
+	if q.Len() != 0 || q.Cap() != minCapacity {
+		t.Fatal("expected empty queue at minimum capacity")
+	}
+	for i := range q.buf {
+		if q.buf[i] != 0 {
+			t.Fatal("queue has non-zero discarded elements")
+		}
+	}
+
+	for i := range 20 {
+		q.PushFront(i)
+	}
+	if q.DiscardFront(25) != 20 || q.Len() != 0 {
+		t.Fatal("expected all items discarded")
+	}
+
+	err := recoverError(func() { q.DiscardFront(-1) })
+	if !errors.Is(err, ErrNegativeCount) {
+		t.Fatal("expected ErrNegativeCount, got", err)
+	}
+	err = recoverError(func() { q.DiscardBack(-1) })
+	if !errors.Is(err, ErrNegativeCount) {
+		t.Fatal("expected ErrNegativeCount, got", err)
+	}
+}
+
+func TestMaxLenDrop(t *testing.T) {
+	var q Deque[int]
+	var evicted []int
+	q.SetEvictFunc(func(item int) {
+		evicted = append(evicted, item)
+	})
+	q.SetMaxLen(3, OverflowDrop)
+	if q.MaxLen() != 3 {
+		t.Fatal("wrong max length")
+	}
+
+	for i := range 5 {
+		q.PushBack(i)
+	}
+	if !slices.Equal(q.AppendToSlice(nil), []int{2, 3, 4}) {
+		t.Fatal("wrong contents after PushBack:", q.AppendToSlice(nil))
+	}
+	if !slices.Equal(evicted, []int{0, 1}) {
+		t.Fatal("wrong evicted items:", evicted)
+	}
+
+	evicted = evicted[:0]
+	q.PushFront(1)
+	if !slices.Equal(q.AppendToSlice(nil), []int{1, 2, 3}) {
+		t.Fatal("wrong contents after PushFront:", q.AppendToSlice(nil))
+	}
+	if !slices.Equal(evicted, []int{4}) {
+		t.Fatal("wrong evicted items:", evicted)
+	}
+
+	evicted = evicted[:0]
+	q.Insert(2, 9)
+	if !slices.Equal(q.AppendToSlice(nil), []int{2, 9, 3}) {
+		t.Fatal("wrong contents after Insert:", q.AppendToSlice(nil))
+	}
+	if !slices.Equal(evicted, []int{1}) {
+		t.Fatal("wrong evicted items:", evicted)
+	}
+
+	evicted = evicted[:0]
+	q.CopyInSlice([]int{5, 6, 7, 8, 9})
+	if !slices.Equal(q.AppendToSlice(nil), []int{7, 8, 9}) {
+		t.Fatal("wrong contents after CopyInSlice:", q.AppendToSlice(nil))
+	}
+	if !slices.Equal(evicted, []int{5, 6}) {
+		t.Fatal("wrong evicted items:", evicted)
+	}
+
+	var src Deque[int]
+	src.CopyInSlice([]int{10, 11, 12, 13})
+	evicted = evicted[:0]
+	if q.Copy(src) != 3 {
+		t.Fatal("wrong copy count")
+	}
+	if !slices.Equal(q.AppendToSlice(nil), []int{11, 12, 13}) {
+		t.Fatal("wrong contents after Copy:", q.AppendToSlice(nil))
+	}
+	if !slices.Equal(evicted, []int{10}) {
+		t.Fatal("wrong evicted items:", evicted)
+	}
+
+	evicted = evicted[:0]
+	q.SetMaxLen(1, OverflowDrop)
+	if !slices.Equal(q.AppendToSlice(nil), []int{13}) {
+		t.Fatal("wrong contents after SetMaxLen:", q.AppendToSlice(nil))
+	}
+	if !slices.Equal(evicted, []int{11, 12}) {
+		t.Fatal("wrong evicted items:", evicted)
+	}
+
+	q.SetMaxLen(0, OverflowDrop)
+	for i := range 100 {
+		q.PushBack(i)
+	}
+	if q.Len() != 101 {
+		t.Fatal("expected no maximum length")
+	}
+
+	assertPanics(t, "should panic with negative max length", func() {
+		q.SetMaxLen(-1, OverflowDrop)
+	})
+}
+
+func TestMaxLenReject(t *testing.T) {
+	var q Deque[int]
+	var rejected []int
+	q.SetEvictFunc(func(item int) {
+		rejected = append(rejected, item)
+	})
+	q.SetMaxLen(3, OverflowReject)
+
+	for i := range 5 {
+		q.PushBack(i)
+	}
+	q.PushFront(-1)
+	q.Insert(1, -2)
+	if !slices.Equal(q.AppendToSlice(nil), []int{0, 1, 2}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+	if !slices.Equal(rejected, []int{3, 4, -1, -2}) {
+		t.Fatal("wrong rejected items:", rejected)
+	}
+
+	rejected = rejected[:0]
+	q.CopyInSlice([]int{5, 6, 7, 8, 9})
+	if !slices.Equal(q.AppendToSlice(nil), []int{5, 6, 7}) {
+		t.Fatal("wrong contents after CopyInSlice:", q.AppendToSlice(nil))
+	}
+	if !slices.Equal(rejected, []int{8, 9}) {
+		t.Fatal("wrong rejected items:", rejected)
+	}
+
+	var src Deque[int]
+	src.CopyInSlice([]int{10, 11, 12, 13})
+	rejected = rejected[:0]
+	q.Copy(src)
+	if !slices.Equal(q.AppendToSlice(nil), []int{10, 11, 12}) {
+		t.Fatal("wrong contents after Copy:", q.AppendToSlice(nil))
+	}
+	if !slices.Equal(rejected, []int{13}) {
+		t.Fatal("wrong rejected items:", rejected)
+	}
+}
+
+func TestMaxLenPanic(t *testing.T) {
+	var q Deque[int]
+	q.SetMaxLen(2, OverflowPanic)
+	q.PushBack(1)
+	q.PushBack(2)
+
+	for name, f := range map[string]func(){
+		"PushBack":    func() { q.PushBack(3) },
+		"PushFront":   func() { q.PushFront(3) },
+		"Insert":      func() { q.Insert(1, 3) },
+		"CopyInSlice": func() { q.CopyInSlice([]int{1, 2, 3}) },
+		"Copy": func() {
+			var src Deque[int]
+			src.CopyInSlice([]int{1, 2, 3})
+			q.Copy(src)
+		},
+	} {
+		err := recoverError(f)
+		if !errors.Is(err, ErrFull) {
+			t.Errorf("%s: expected ErrFull, got %v", name, err)
+		}
+	}
+	if !slices.Equal(q.AppendToSlice(nil), []int{1, 2}) {
+		t.Fatal("deque modified by overflow:", q.AppendToSlice(nil))
+	}
+
+	q.PopFront()
+	q.PushBack(3)
+	if !slices.Equal(q.AppendToSlice(nil), []int{2, 3}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+}
+
+func TestGrowShrinkToFit(t *testing.T) {
+	var q Deque[int]
+	q.Grow(100)
+	for i := range 3 {
+		q.PushBack(i)
+	}
+	for range q.IterPopFront() {
+		break
+	}
+	if q.Cap() != minCapacity {
+		t.Fatal("expected capacity", minCapacity, "got", q.Cap())
+	}
+
+	var b Deque[int]
+	b.SetBaseCap(64)
+	b.CopyInSlice([]int{1, 2, 3})
+	if b.Cap() != 64 {
+		t.Fatal("expected capacity 64, got", b.Cap())
+	}
+}
+
+func TestShrinkPolicy(t *testing.T) {
+	fill := func(q *Deque[int], n int) {
+		for i := range n {
+			q.PushBack(i)
+		}
+	}
+
+	var q Deque[int]
+	q.SetShrinkPolicy(ShrinkPolicy{Never: true})
+	fill(&q, 1000)
+	for range 999 {
+		q.PopFront()
+	}
+	for range q.IterPopBack() {
+	}
+	if q.Cap() != 1024 {
+		t.Fatal("expected capacity 1024, got", q.Cap())
+	}
+	q.PushBack(1)
+	q.ShrinkToFit()
+	if q.Cap() != minCapacity || q.Front() != 1 {
+		t.Fatal("expected ShrinkToFit to shrink to base capacity")
+	}
+
+	// Shrink when an eighth full, leaving the deque at most half full.
+	q.SetShrinkPolicy(ShrinkPolicy{FillRatio: 0.125})
+	fill(&q, 1023)
+	for q.Len() > 129 {
+		q.PopFront()
+	}
+	if q.Cap() != 1024 {
+		t.Fatal("expected capacity 1024, got", q.Cap())
+	}
+	q.PopFront()
+	if q.Cap() != 256 {
+		t.Fatal("expected capacity 256, got", q.Cap())
+	}
+
+	q.SetShrinkPolicy(ShrinkPolicy{Delay: 10})
+	q.Clear()
+	fill(&q, 1024)
+	for q.Len() > 256 {
+		q.PopBack()
+	}
+	// A removal that leaves the deque above the fill ratio restarts the delay.
+	for range 20 {
+		q.PushBack(0)
+		q.PushBack(0)
+		q.PopBack()
+		q.PopBack()
+	}
+	for range 8 {
+		q.PopBack()
+	}
+	if q.Cap() != 1024 {
+		t.Fatal("shrank before delay")
+	}
+	q.PopBack()
+	if q.Cap() != 512 || q.Len() != 247 {
+		t.Fatal("expected capacity 512 after delay, got", q.Cap())
+	}
+
+	// An adaptive deque keeps the capacity for its high-water mark.
+	q.SetShrinkPolicy(ShrinkPolicy{Adaptive: true})
+	q.Clear()
+	for range 3 {
+		fill(&q, 1024)
+		for q.Len() != 0 {
+			q.PopFront()
+		}
+		if q.Cap() != 1024 {
+			t.Fatal("expected capacity 1024, got", q.Cap())
+		}
+	}
+	// The high-water mark falls when it is not reached again.
+	for range 3 * 1024 {
+		q.PushBack(0)
+		q.PopFront()
+	}
+	if q.Cap() == 1024 {
+		t.Fatal("expected capacity to shrink")
+	}
+
+	q.SetShrinkPolicy(ShrinkPolicy{})
+	if q.shrink != nil {
+		t.Fatal("expected default policy")
+	}
+	err := recoverError(func() { q.SetShrinkPolicy(ShrinkPolicy{Delay: -1}) })
+	if !errors.Is(err, ErrNegativeCount) {
+		t.Fatal("expected ErrNegativeCount, got", err)
+	}
+}
+
+func TestIncrementalResize(t *testing.T) {
+	var q Deque[int]
+	q.SetIncrementalResize(true)
+	for i := range 64 {
+		q.PushBack(i)
+	}
+	if q.old != nil {
+		t.Fatal("expected resize to be complete")
+	}
+	q.PushBack(64)
+	if q.Cap() != 128 {
+		t.Fatal("expected capacity 128, got", q.Cap())
+	}
+	if q.old == nil {
+		t.Fatal("expected resize to be in progress")
+	}
+	for i := range q.Len() {
+		if q.At(i) != i {
+			t.Fatalf("expected %d at index %d, got %d", i, i, q.At(i))
+		}
+	}
+	q.Set(0, -1)
+	if q.Front() != -1 || q.Back() != 64 {
+		t.Fatal("wrong front or back during resize")
+	}
+	// The remaining items are moved by the following pushes.
+	for i := 65; i < 64+64/2; i++ {
+		q.PushBack(i)
+	}
+	if q.old != nil {
+		t.Fatal("expected resize to be complete")
+	}
+	if q.PopFront() != -1 {
+		t.Fatal("wrong value popped")
+	}
+	for i := 1; q.Len() != 0; i++ {
+		if q.PopFront() != i {
+			t.Fatal("wrong value popped")
+		}
+	}
+	if q.Cap() != minCapacity {
+		t.Fatal("expected capacity", minCapacity, "got", q.Cap())
+	}
+
+	q.SetIncrementalResize(true)
+	for i := range 65 {
+		q.PushFront(i)
+	}
+	q.SetIncrementalResize(false)
+	if q.old != nil {
+		t.Fatal("expected resize to be complete")
+	}
+	if q.Front() != 64 || q.Back() != 0 {
+		t.Fatal("wrong front or back")
+	}
+}
+
+func TestIncrementalResizeRandom(t *testing.T) {
+	rng := rand.New(rand.NewPCG(7, 8))
+	var q, ref Deque[int]
+	q.SetIncrementalResize(true)
+	var next int
+	for round := range 20000 {
+		// Favor pushes in the first half and pops in the second half, so that
+		// the deques repeatedly grow and shrink.
+		push := rng.IntN(10) < 7
+		if (round/2500)%2 == 1 {
+			push = !push
+		}
+		switch op := rng.IntN(60); {
+		case op < 40 && push:
+			next++
+			if op%2 == 0 {
+				q.PushBack(next)
+				ref.PushBack(next)
+			} else {
+				q.PushFront(next)
+				ref.PushFront(next)
+			}
+		case op < 40:
+			if ref.Len() == 0 {
+				continue
+			}
+			var x, y int
+			if op%2 == 0 {
+				x, y = q.PopFront(), ref.PopFront()
+			} else {
+				x, y = q.PopBack(), ref.PopBack()
+			}
+			if x != y {
+				t.Fatalf("round %d: popped %d, expected %d", round, x, y)
+			}
+		case op < 56:
+			if ref.Len() == 0 {
+				continue
+			}
+			i := rng.IntN(ref.Len())
+			if q.At(i) != ref.At(i) {
+				t.Fatalf("round %d: wrong value at %d", round, i)
+			}
+			next++
+			q.Set(i, next)
+			ref.Set(i, next)
+			j := rng.IntN(ref.Len())
+			q.Swap(i, j)
+			ref.Swap(i, j)
+		case op == 56:
+			if !slices.Equal(q.AppendToSlice(nil), ref.AppendToSlice(nil)) {
+				t.Fatalf("round %d: wrong contents", round)
+			}
+		case op == 57:
+			i := rng.IntN(ref.Len() + 1)
+			next++
+			q.Insert(i, next)
+			ref.Insert(i, next)
+		case op == 58:
+			if ref.Len() == 0 {
+				continue
+			}
+			i := rng.IntN(ref.Len())
+			if q.Remove(i) != ref.Remove(i) {
+				t.Fatalf("round %d: wrong value removed from %d", round, i)
+			}
+		default:
+			n := rng.IntN(7) - 3
+			q.Rotate(n)
+			ref.Rotate(n)
+		}
+		if q.Len() != ref.Len() || q.Cap() != ref.Cap() {
+			t.Fatalf("round %d: length %d capacity %d, expected %d and %d",
+				round, q.Len(), q.Cap(), ref.Len(), ref.Cap())
+		}
+	}
+	out := make([]int, q.Len())
+	q.CopyOutSlice(out)
+	if !slices.Equal(out, ref.AppendToSlice(nil)) {
+		t.Fatal("wrong contents")
+	}
+	q.DiscardBack(q.Len())
+	for i := range q.buf {
+		if q.buf[i] != 0 {
+			t.Fatal("buffer has removed items")
+		}
+	}
+}
+
+func TestNewFromBuffer(t *testing.T) {
+	buf := make([]int, 32)
+	buf[3] = 99
+	q := NewFromBuffer(buf)
+	if q.Len() != 0 || q.Cap() != 32 || buf[3] != 0 {
+		t.Fatal("expected empty deque using cleared buffer")
+	}
+	for i := range 32 {
+		q.PushBack(i)
+	}
+	if &q.buf[0] != &buf[0] {
+		t.Fatal("expected deque to use provided buffer")
+	}
+	for range 30 {
+		q.PopFront()
+	}
+	if q.Cap() != 32 || &q.buf[0] != &buf[0] {
+		t.Fatal("deque shrank below the provided buffer")
+	}
+	for i := range 40 {
+		q.PushFront(i)
+	}
+	if q.Cap() != 64 || q.Len() != 42 {
+		t.Fatal("expected deque to grow past the provided buffer")
+	}
+	if slices.ContainsFunc(buf, func(x int) bool { return x != 0 }) {
+		t.Fatal("expected provided buffer to be cleared after growing")
+	}
+
+	for _, n := range []int{0, 3, 48} {
+		err := recoverError(func() { NewFromBuffer(make([]int, n)) })
+		if !errors.Is(err, ErrBufferSize) {
+			t.Fatalf("expected ErrBufferSize for length %d, got %v", n, err)
+		}
+	}
+	if err := recoverError(func() { NewFixed(make([]int, 6), OverflowDrop) }); !errors.Is(err, ErrBufferSize) {
+		t.Fatal("expected ErrBufferSize, got", err)
+	}
+}
+
+func TestNewFixed(t *testing.T) {
+	buf := make([]int, 8)
+	q := NewFixed(buf, OverflowDrop)
+	if q.MaxLen() != 8 {
+		t.Fatal("expected maximum length 8, got", q.MaxLen())
+	}
+	q.SetBaseCap(64)
+	var next int
+	allocs := testing.AllocsPerRun(100, func() {
+		for range 20 {
+			next++
+			q.PushBack(next)
+		}
+		q.Insert(3, -1)
+		q.PushFrontSlice([]int{-2, -3})
+		q.Rotate(3)
+		q.RemoveRange(1, 2)
+		q.InsertSlice(1, -4, -5)
+		for range 7 {
+			q.PopFront()
+		}
+		q.Grow(7)
+		a, b := q.Reserve(3)
+		if len(a)+len(b) != 3 {
+			t.Fatal("wrong number of reserved positions")
+		}
+		q.Commit(3)
+		q.Clear()
+	})
+	if allocs != 0 {
+		t.Fatal("expected no allocations, got", allocs)
+	}
+	if q.Cap() != 8 || &q.buf[0] != &buf[0] {
+		t.Fatal("expected deque to keep the provided buffer")
+	}
+
+	for i := range 10 {
+		q.PushBack(i)
+	}
+	if q.Front() != 2 || q.Back() != 9 {
+		t.Fatal("expected oldest items to be dropped")
+	}
+	if err := recoverError(func() { q.Grow(1) }); !errors.Is(err, ErrFull) {
+		t.Fatal("expected ErrFull, got", err)
+	}
+	if err := recoverError(func() { q.ReserveFront(1) }); !errors.Is(err, ErrFull) {
+		t.Fatal("expected ErrFull, got", err)
+	}
+
+	q.SetMaxLen(4, OverflowReject)
+	if q.Len() != 4 || q.Front() != 6 || q.Cap() != 8 {
+		t.Fatal("expected items evicted without resizing")
+	}
+	q.PushBack(100)
+	if q.Back() != 9 {
+		t.Fatal("expected item to be rejected")
+	}
+	q.SetMaxLen(0, OverflowPanic)
+	if q.MaxLen() != 8 {
+		t.Fatal("expected maximum length to be capacity, got", q.MaxLen())
+	}
+	for i := range 4 {
+		q.PushFront(i)
+	}
+	if err := recoverError(func() { q.PushBack(1) }); !errors.Is(err, ErrFull) {
+		t.Fatal("expected ErrFull, got", err)
+	}
+	q.CopyInSlice([]int{1, 2, 3})
+	if q.Len() != 3 || &q.buf[0] != &buf[0] {
+		t.Fatal("expected CopyInSlice to use the provided buffer")
+	}
+}
+
+func TestCopyInSliceCopyOutSlice(t *testing.T) {
+	var q Deque[int]
+
+	out := make([]int, 5)
+	n := q.CopyOutSlice(out)
+	if n != 0 {
+		t.Fatal("expected nothing replaced")
+	}
+	content1 := []int{1, 2, 3}
+	q.CopyInSlice(content1)
+	if q.Front() != 1 {
+		t.Fatal("wrong value for Fromt")
+	}
+	if q.Back() != 3 {
 		t.Fatal("wrong value for Back")
 	}
 
@@ -1190,75 +2753,376 @@ func TestCopyInSliceCopyOutSlice(t *testing.T) {
 		t.Fatal("wrong last element in output")
 	}
 
-	first := q.Front()
-	last := q.At(len(out) - 1)
-	n = q.CopyOutSlice(out)
-	if n != len(out) {
-		t.Fatalf("expected %d replaced items", len(out))
+	first := q.Front()
+	last := q.At(len(out) - 1)
+	n = q.CopyOutSlice(out)
+	if n != len(out) {
+		t.Fatalf("expected %d replaced items", len(out))
+	}
+	if out[0] != first {
+		t.Fatalf("expected previous[0] to be %d, got %d", first, out[0])
+	}
+	if out[len(out)-1] != last {
+		t.Fatalf("expected last previous to be %d, got %d", last, out[len(out)-1])
+	}
+
+	q.CopyInSlice(content5)
+	if q.Len() != len(content5) {
+		t.Fatalf("q should have %d items", len(content5))
+	}
+	for i, v := range content5 {
+		if q.At(i) != v {
+			t.Fatal("unexpected content after replace")
+		}
+	}
+}
+
+func TestAppendToSlice(t *testing.T) {
+	var q Deque[int]
+	content := []int{1, 2, 3}
+	q.CopyInSlice(content)
+
+	out := q.AppendToSlice(nil)
+	if !slices.Equal(content, out) {
+		t.Fatal("did not get expected content")
+	}
+
+	out = q.AppendToSlice(out)
+	if len(out) != 2*len(content) {
+		t.Fatal("worng output length")
+	}
+	if !slices.Equal(content, out[:len(content)]) {
+		t.Fatal("did not get expected content at start of out")
+	}
+	if !slices.Equal(content, out[len(content):]) {
+		t.Fatal("did not get expected content at end of out")
+	}
+
+	q.Clear()
+	for i := 0; i < q.Cap(); i++ {
+		q.PushBack(i)
+	}
+	for i := 0; i < q.Cap()/2; i++ {
+		q.PopFront()
+		q.PushBack(i + 100)
+	}
+
+	out = q.AppendToSlice(out[:0])
+	if len(out) != q.Len() {
+		t.Fatal("wrong output length")
+	}
+	if out[0] != q.Front() {
+		t.Fatal("worng first element")
+	}
+	if out[len(out)-1] != q.Back() {
+		t.Fatal("worng last element")
+	}
+
+	q.Clear()
+	newOut := q.AppendToSlice(out)
+	if !slices.Equal(out, newOut) {
+		t.Fatal("expected same slice returned")
+	}
+}
+
+func TestAsSlices(t *testing.T) {
+	var q Deque[int]
+	front, back := q.AsSlices()
+	if front != nil || back != nil {
+		t.Fatal("expected nil slices for empty deque")
+	}
+
+	q.PushBackSlice([]int{1, 2, 3})
+	front, back = q.AsSlices()
+	if !slices.Equal(front, []int{1, 2, 3}) || back != nil {
+		t.Fatal("wrong slices for contiguous deque:", front, back)
+	}
+
+	// Fill the buffer so that head is at the start.
+	for i := 4; i <= q.Cap(); i++ {
+		q.PushBack(i)
+	}
+	front, back = q.AsSlices()
+	if len(front) != q.Len() || back != nil {
+		t.Fatal("wrong slices for full deque:", front, back)
+	}
+
+	// Wrap the items around the end of the buffer.
+	q.Clear()
+	for i := range 10 {
+		q.PushBack(i)
+		q.PushFront(-i)
+	}
+	front, back = q.AsSlices()
+	if len(back) == 0 {
+		t.Fatal("expected items to wrap")
+	}
+	if !slices.Equal(slices.Concat(front, back), q.AppendToSlice(nil)) {
+		t.Fatal("slices do not match contents:", front, back)
+	}
+	if cap(front) != len(front) || cap(back) != len(back) {
+		t.Fatal("slice capacity should be limited to length")
+	}
+
+	front, back = q.AsMutSlices()
+	front[0] = 100
+	back[len(back)-1] = 200
+	if q.Front() != 100 || q.Back() != 200 {
+		t.Fatal("modifying slices did not modify deque")
+	}
+	_ = append(front, 300)
+	if q.At(len(front)) == 300 {
+		t.Fatal("appending to slice modified deque")
+	}
+}
+
+func TestMakeContiguous(t *testing.T) {
+	var q Deque[int]
+	if q.MakeContiguous() != nil {
+		t.Fatal("expected nil for empty deque")
+	}
+
+	q.PushBackSlice([]int{1, 2, 3})
+	q.PopFront()
+	s := q.MakeContiguous()
+	if !slices.Equal(s, []int{2, 3}) {
+		t.Fatal("wrong contents:", s)
+	}
+	if q.head != 1 {
+		t.Fatal("contiguous items should not be moved")
+	}
+
+	// Items wrapped with enough free space for block copies, and with too
+	// little free space, which rotates the buffer.
+	for _, n := range []int{3, 5, 12, 14, 15, 16} {
+		q.Clear()
+		for i := range n - 2 {
+			q.PushBack(i)
+		}
+		for i := range 2 {
+			q.PushFront(-1 - i)
+		}
+		want := q.AppendToSlice(nil)
+		cap := q.Cap()
+		s = q.MakeContiguous()
+		if !slices.Equal(s, want) {
+			t.Fatalf("n=%d: wrong contents: %v", n, s)
+		}
+		if q.head != 0 || q.Cap() != cap {
+			t.Fatalf("n=%d: expected head 0 and same capacity", n)
+		}
+		if &s[0] != &q.buf[0] {
+			t.Fatalf("n=%d: expected slice to alias buffer", n)
+		}
+		for i := n; i < len(q.buf); i++ {
+			if q.buf[i] != 0 {
+				t.Fatalf("n=%d: non-zero value in unused buffer position", n)
+			}
+		}
+		q.PushBack(100)
+		q.PushFront(-100)
+		if q.Front() != -100 || q.Back() != 100 || q.Len() != n+2 {
+			t.Fatalf("n=%d: wrong state after MakeContiguous", n)
+		}
+	}
+}
+
+func TestReserveCommit(t *testing.T) {
+	var q Deque[int]
+	if a, b := q.Reserve(0); a != nil || b != nil {
+		t.Fatal("expected nil slices for zero reservation")
+	}
+
+	a, b := q.Reserve(5)
+	if len(a)+len(b) != 5 {
+		t.Fatal("wrong number of reserved positions")
+	}
+	for i := range a {
+		a[i] = i
+	}
+	q.Commit(3)
+	if !slices.Equal(q.AppendToSlice(nil), []int{0, 1, 2}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+
+	// Reserve space that wraps around the end of the buffer.
+	for i := range 10 {
+		q.PushBack(i)
+		q.PopFront()
 	}
-	if out[0] != first {
-		t.Fatalf("expected previous[0] to be %d, got %d", first, out[0])
+	want := q.AppendToSlice(nil)
+	a, b = q.Reserve(8)
+	if len(b) == 0 {
+		t.Fatal("expected reserved space to wrap")
 	}
-	if out[len(out)-1] != last {
-		t.Fatalf("expected last previous to be %d, got %d", last, out[len(out)-1])
+	items := []int{10, 11, 12, 13, 14, 15, 16, 17}
+	n := copy(a, items)
+	copy(b, items[n:])
+	q.Commit(len(items))
+	want = append(want, items...)
+	if !slices.Equal(q.AppendToSlice(nil), want) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
 	}
 
-	q.CopyInSlice(content5)
-	if q.Len() != len(content5) {
-		t.Fatalf("q should have %d items", len(content5))
-	}
-	for i, v := range content5 {
-		if q.At(i) != v {
-			t.Fatal("unexpected content after replace")
+	// Reserve more space than is available.
+	c := q.Cap()
+	a, b = q.Reserve(c)
+	if q.Cap() != 2*c || len(a)+len(b) != c {
+		t.Fatal("expected buffer to grow once for reservation")
+	}
+	q.Commit(0)
+	if !slices.Equal(q.AppendToSlice(nil), want) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+
+	// Commit only what was reserved, only once, and only if the deque was not
+	// modified since the reservation.
+	for name, f := range map[string]func(){
+		"more than reserved": func() {
+			q.Reserve(2)
+			q.Commit(3)
+		},
+		"negative": func() {
+			q.Reserve(2)
+			q.Commit(-1)
+		},
+		"twice": func() {
+			q.Reserve(2)
+			q.Commit(1)
+			q.Commit(1)
+		},
+		"after modification": func() {
+			q.Reserve(2)
+			q.PushBack(1)
+			q.Commit(1)
+		},
+		"reserved at front": func() {
+			q.ReserveFront(2)
+			q.Commit(1)
+		},
+		"without reservation": func() {
+			var empty Deque[int]
+			empty.Commit(0)
+		},
+	} {
+		if err := recoverError(f); !errors.Is(err, ErrOutOfRange) {
+			t.Errorf("Commit %s: expected ErrOutOfRange, got %v", name, err)
 		}
 	}
+	q.Reserve(0)
+	q.Commit(0)
+
+	err := recoverError(func() { q.Reserve(-1) })
+	if !errors.Is(err, ErrNegativeCount) {
+		t.Fatal("expected ErrNegativeCount, got", err)
+	}
 }
 
-func TestAppendToSlice(t *testing.T) {
+func TestReserveCommitFront(t *testing.T) {
 	var q Deque[int]
-	content := []int{1, 2, 3}
-	q.CopyInSlice(content)
-
-	out := q.AppendToSlice(nil)
-	if !slices.Equal(content, out) {
-		t.Fatal("did not get expected content")
-	}
+	q.PushBackSlice([]int{5, 6})
 
-	out = q.AppendToSlice(out)
-	if len(out) != 2*len(content) {
-		t.Fatal("worng output length")
-	}
-	if !slices.Equal(content, out[:len(content)]) {
-		t.Fatal("did not get expected content at start of out")
+	a, b := q.ReserveFront(5)
+	if len(a)+len(b) != 5 {
+		t.Fatal("wrong number of reserved positions")
 	}
-	if !slices.Equal(content, out[len(content):]) {
-		t.Fatal("did not get expected content at end of out")
-	}
-
-	q.Clear()
-	for i := 0; i < q.Cap(); i++ {
-		q.PushBack(i)
+	items := []int{0, 1, 2, 3, 4}
+	n := copy(a, items)
+	copy(b, items[n:])
+	q.CommitFront(3)
+	if !slices.Equal(q.AppendToSlice(nil), []int{2, 3, 4, 5, 6}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
 	}
-	for i := 0; i < q.Cap()/2; i++ {
-		q.PopFront()
-		q.PushBack(i + 100)
+	q.PushFront(1)
+	if q.Front() != 1 || q.Len() != 6 {
+		t.Fatal("wrong state after CommitFront")
 	}
 
-	out = q.AppendToSlice(out[:0])
-	if len(out) != q.Len() {
-		t.Fatal("wrong output length")
+	err := recoverError(func() { q.ReserveFront(-1) })
+	if !errors.Is(err, ErrNegativeCount) {
+		t.Fatal("expected ErrNegativeCount, got", err)
 	}
-	if out[0] != q.Front() {
-		t.Fatal("worng first element")
+	q.ReserveFront(2)
+	err = recoverError(func() { q.CommitFront(3) })
+	if !errors.Is(err, ErrOutOfRange) {
+		t.Fatal("expected ErrOutOfRange, got", err)
 	}
-	if out[len(out)-1] != q.Back() {
-		t.Fatal("worng last element")
+	q.Reserve(2)
+	err = recoverError(func() { q.CommitFront(1) })
+	if !errors.Is(err, ErrOutOfRange) {
+		t.Fatal("expected ErrOutOfRange, got", err)
 	}
+}
 
-	q.Clear()
-	newOut := q.AppendToSlice(out)
-	if !slices.Equal(out, newOut) {
-		t.Fatal("expected same slice returned")
+func TestCommitMaxLen(t *testing.T) {
+	write := func(a, b []int, items ...int) {
+		n := copy(a, items)
+		copy(b, items[n:])
+	}
+	var q Deque[int]
+	var evicted []int
+	q.SetEvictFunc(func(item int) {
+		evicted = append(evicted, item)
+	})
+	q.PushBackSlice([]int{0, 1, 2})
+	q.SetMaxLen(4, OverflowDrop)
+
+	a, b := q.Reserve(3)
+	write(a, b, 3, 4, 5)
+	q.Commit(3)
+	if !slices.Equal(q.AppendToSlice(nil), []int{2, 3, 4, 5}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+	if !slices.Equal(evicted, []int{0, 1}) {
+		t.Fatal("wrong evicted items:", evicted)
+	}
+
+	evicted = evicted[:0]
+	a, b = q.ReserveFront(2)
+	write(a, b, 0, 1)
+	q.CommitFront(2)
+	if !slices.Equal(q.AppendToSlice(nil), []int{0, 1, 2, 3}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+	if !slices.Equal(evicted, []int{5, 4}) {
+		t.Fatal("wrong evicted items:", evicted)
+	}
+
+	q.SetMaxLen(5, OverflowReject)
+	evicted = evicted[:0]
+	a, b = q.Reserve(2)
+	write(a, b, 4, 5)
+	q.Commit(2)
+	a, b = q.ReserveFront(2)
+	write(a, b, -2, -1)
+	q.CommitFront(2)
+	if !slices.Equal(q.AppendToSlice(nil), []int{0, 1, 2, 3, 4}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+	if !slices.Equal(evicted, []int{5, -2, -1}) {
+		t.Fatal("wrong rejected items:", evicted)
+	}
+
+	q.SetMaxLen(5, OverflowPanic)
+	a, b = q.Reserve(1)
+	write(a, b, 9)
+	err := recoverError(func() { q.Commit(1) })
+	if !errors.Is(err, ErrFull) {
+		t.Fatal("expected ErrFull, got", err)
+	}
+	a, b = q.ReserveFront(1)
+	write(a, b, 9)
+	err = recoverError(func() { q.CommitFront(1) })
+	if !errors.Is(err, ErrFull) {
+		t.Fatal("expected ErrFull, got", err)
+	}
+	if !slices.Equal(q.AppendToSlice(nil), []int{0, 1, 2, 3, 4}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+	for i := range q.buf {
+		if q.buf[i] == 9 {
+			t.Fatal("uncommitted item left in buffer")
+		}
 	}
 }
 
@@ -1357,6 +3221,230 @@ func TestPopFrontPanic(t *testing.T) {
 	q.PushBack(0)
 }
 
+func TestTryFrontBack(t *testing.T) {
+	var q Deque[string]
+	if _, ok := q.TryFront(); ok {
+		t.Error("TryFront should fail on empty queue")
+	}
+	if _, ok := q.TryBack(); ok {
+		t.Error("TryBack should fail on empty queue")
+	}
+	if _, ok := q.TryPopFront(); ok {
+		t.Error("TryPopFront should fail on empty queue")
+	}
+	if _, ok := q.TryPopBack(); ok {
+		t.Error("TryPopBack should fail on empty queue")
+	}
+
+	q.PushBack("foo")
+	q.PushBack("bar")
+	q.PushBack("baz")
+	if x, ok := q.TryFront(); !ok || x != "foo" {
+		t.Error("wrong value at front of queue")
+	}
+	if x, ok := q.TryBack(); !ok || x != "baz" {
+		t.Error("wrong value at back of queue")
+	}
+	if x, ok := q.TryPopFront(); !ok || x != "foo" {
+		t.Error("wrong value removed from front of queue")
+	}
+	if x, ok := q.TryPopBack(); !ok || x != "baz" {
+		t.Error("wrong value removed from back of queue")
+	}
+	if x, ok := q.TryPopBack(); !ok || x != "bar" {
+		t.Error("wrong value removed from back of queue")
+	}
+	if q.Len() != 0 {
+		t.Error("q.Len() =", q.Len(), "expected 0")
+	}
+	if _, ok := q.TryPopFront(); ok {
+		t.Error("TryPopFront should fail on emptied queue")
+	}
+
+	var nilDeque *Deque[int]
+	if _, ok := nilDeque.TryFront(); ok {
+		t.Error("TryFront should fail on nil deque")
+	}
+	if _, ok := nilDeque.TryPopBack(); ok {
+		t.Error("TryPopBack should fail on nil deque")
+	}
+}
+
+func TestTryPopShrink(t *testing.T) {
+	var q Deque[int]
+	const size = minCapacity * 2
+
+	for i := range size {
+		q.PushBack(i)
+	}
+	bufLen := len(q.buf)
+	for i := range size {
+		x, ok := q.TryPopFront()
+		if !ok || x != i {
+			t.Fatal("TryPopFront() =", x, "expected", i)
+		}
+	}
+	if len(q.buf) == bufLen {
+		t.Error("queue buffer did not shrink")
+	}
+}
+
+func TestTryAtSet(t *testing.T) {
+	var q Deque[int]
+	if _, ok := q.TryAt(0); ok {
+		t.Error("TryAt should fail on empty queue")
+	}
+	if q.TrySet(0, 1) {
+		t.Error("TrySet should fail on empty queue")
+	}
+
+	for i := range 1000 {
+		q.PushBack(i)
+		if !q.TrySet(i, i+50) {
+			t.Fatal("TrySet failed for valid index", i)
+		}
+	}
+	for j := range q.Len() {
+		if x, ok := q.TryAt(j); !ok || x != j+50 {
+			t.Errorf("index %d doesn't contain %d", j, j+50)
+		}
+	}
+
+	for _, i := range []int{-1, q.Len(), q.Len() + 10} {
+		if _, ok := q.TryAt(i); ok {
+			t.Error("TryAt should fail for index", i)
+		}
+		if q.TrySet(i, 1) {
+			t.Error("TrySet should fail for index", i)
+		}
+	}
+}
+
+func TestTryRemove(t *testing.T) {
+	q := new(Deque[rune])
+	if _, ok := q.TryRemove(0); ok {
+		t.Error("TryRemove should fail on empty queue")
+	}
+	for _, x := range "ABCDEFG" {
+		q.PushBack(x)
+	}
+	if x, ok := q.TryRemove(4); !ok || x != 'E' { // ABCDFG
+		t.Error("expected E from position 4")
+	}
+	if x, ok := q.TryRemove(0); !ok || x != 'A' { // BCDFG
+		t.Error("expected A from position 0")
+	}
+	if _, ok := q.TryRemove(-1); ok {
+		t.Error("TryRemove should fail at negative index")
+	}
+	if _, ok := q.TryRemove(q.Len()); ok {
+		t.Error("TryRemove should fail out of range")
+	}
+	for i, x := range "BCDFG" {
+		if q.At(i) != x {
+			t.Error("expected", x, "at position", i)
+		}
+	}
+}
+
+func TestTrySwap(t *testing.T) {
+	var q Deque[string]
+	if q.TrySwap(0, 0) {
+		t.Error("TrySwap should fail on empty queue")
+	}
+
+	q.PushBack("a")
+	q.PushBack("b")
+	q.PushBack("c")
+
+	if !q.TrySwap(0, 2) {
+		t.Fatal("TrySwap failed for valid indexes")
+	}
+	if q.Front() != "c" || q.Back() != "a" {
+		t.Fatal("wrong values after swap")
+	}
+	if q.TrySwap(1, 3) || q.TrySwap(-1, 1) {
+		t.Fatal("TrySwap should fail for out of range index")
+	}
+	if q.At(1) != "b" {
+		t.Fatal("failed TrySwap modified the queue")
+	}
+}
+
+func TestPanicErrors(t *testing.T) {
+	var q Deque[int]
+
+	for name, f := range map[string]func(){
+		"PopFront": func() { q.PopFront() },
+		"PopBack":  func() { q.PopBack() },
+		"Front":    func() { q.Front() },
+		"Back":     func() { q.Back() },
+	} {
+		err := recoverError(f)
+		if !errors.Is(err, ErrEmpty) {
+			t.Errorf("%s: expected ErrEmpty, got %v", name, err)
+		}
+	}
+
+	err := recoverError(func() { q.Grow(-1) })
+	if !errors.Is(err, ErrNegativeCount) {
+		t.Error("expected ErrNegativeCount, got", err)
+	}
+
+	q.PushBack(1)
+	q.PushBack(2)
+	err = recoverError(func() { q.At(5) })
+	if !errors.Is(err, ErrOutOfRange) {
+		t.Error("expected ErrOutOfRange, got", err)
+	}
+	var idxErr *IndexError
+	if !errors.As(err, &idxErr) {
+		t.Fatal("expected *IndexError, got", err)
+	}
+	if idxErr.Index != 5 || idxErr.Len != 2 {
+		t.Errorf("wrong IndexError contents: %+v", idxErr)
+	}
+	if idxErr.Error() != "deque: index out of range 5 with length 2" {
+		t.Error("wrong error message:", idxErr.Error())
+	}
+	for _, f := range []func(){
+		func() { q.Set(-1, 0) },
+		func() { q.Remove(2) },
+		func() { q.Swap(0, 3) },
+	} {
+		if !errors.As(recoverError(f), &idxErr) {
+			t.Error("expected *IndexError")
+		}
+	}
+
+	err = recoverError(func() {
+		for range q.Iter() {
+			q.PushBack(3)
+		}
+	})
+	if !errors.Is(err, ErrModifiedDuringIteration) {
+		t.Error("expected ErrModifiedDuringIteration, got", err)
+	}
+	err = recoverError(func() {
+		for range q.RIter() {
+			q.PopFront()
+		}
+	})
+	if !errors.Is(err, ErrModifiedDuringIteration) {
+		t.Error("expected ErrModifiedDuringIteration, got", err)
+	}
+}
+
+// recoverError calls f and returns the error that f panics with, or nil if f
+// does not panic with an error.
+func recoverError(f func()) (err error) {
+	defer func() {
+		err, _ = recover().(error)
+	}()
+	f()
+	return nil
+}
+
 func assertPanics(t *testing.T, name string, f func()) {
 	defer func() {
 		if r := recover(); r == nil {
@@ -1381,6 +3469,14 @@ func BenchmarkPushBack(b *testing.B) {
 	}
 }
 
+func BenchmarkPushBackIncremental(b *testing.B) {
+	var q Deque[int]
+	q.SetIncrementalResize(true)
+	for i := 0; i < b.N; i++ {
+		q.PushBack(i)
+	}
+}
+
 func BenchmarkSerial(b *testing.B) {
 	var q Deque[int]
 	for i := 0; i < b.N; i++ {
@@ -1401,6 +3497,56 @@ func BenchmarkSerialReverse(b *testing.B) {
 	}
 }
 
+func BenchmarkPushBackSlice(b *testing.B) {
+	items := make([]int, 1000)
+	var q Deque[int]
+	for i := 0; i < b.N; i++ {
+		q.PushBackSlice(items)
+		q.Clear()
+	}
+}
+
+func BenchmarkPushBackLoop(b *testing.B) {
+	items := make([]int, 1000)
+	var q Deque[int]
+	for i := 0; i < b.N; i++ {
+		for _, item := range items {
+			q.PushBack(item)
+		}
+		q.Clear()
+	}
+}
+
+// benchSum keeps the compiler from discarding the results of At in benchmarks.
+var benchSum int
+
+// BenchmarkIndexedLoop compares a loop that reads items with At to one that
+// ranges over All.
+func BenchmarkIndexedLoop(b *testing.B) {
+	var q Deque[int]
+	for i := range 1000 {
+		q.PushFront(i)
+	}
+	b.Run("At", func(b *testing.B) {
+		var sum int
+		for i := 0; i < b.N; i++ {
+			for j := range q.Len() {
+				sum += j * q.At(j)
+			}
+		}
+		benchSum = sum
+	})
+	b.Run("All", func(b *testing.B) {
+		var sum int
+		for i := 0; i < b.N; i++ {
+			for j, item := range q.All() {
+				sum += j * item
+			}
+		}
+		benchSum = sum
+	})
+}
+
 func BenchmarkRotate(b *testing.B) {
 	q := new(Deque[int])
 	for i := 0; i < b.N; i++ {
@@ -1413,6 +3559,21 @@ func BenchmarkRotate(b *testing.B) {
 	}
 }
 
+func BenchmarkRotateLarge(b *testing.B) {
+	const size = 1_000_000
+	q := new(Deque[int])
+	for i := range size {
+		q.PushBack(i)
+	}
+	for _, n := range []int{1000, size / 3, -size / 3, 2 * size / 3} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				q.Rotate(n)
+			}
+		})
+	}
+}
+
 func BenchmarkInsert(b *testing.B) {
 	q := new(Deque[int])
 	for i := 0; i < b.N; i++ {
@@ -1435,6 +3596,59 @@ func BenchmarkRemove(b *testing.B) {
 	}
 }
 
+func BenchmarkInsertSlice(b *testing.B) {
+	q := new(Deque[int])
+	for i := 0; i < 100000; i++ {
+		q.PushBack(i)
+	}
+	items := make([]int, 100)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.InsertSlice(q.Len()/2, items...)
+		q.RemoveRange(q.Len()/2, q.Len()/2+len(items))
+	}
+}
+
+// largeItem is an element type that is expensive to copy one at a time.
+type largeItem struct {
+	vals [16]int64
+}
+
+func BenchmarkInsertLarge(b *testing.B) {
+	q := new(Deque[largeItem])
+	for i := 0; i < b.N; i++ {
+		q.PushBack(largeItem{})
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.Insert(q.Len()/2, largeItem{})
+	}
+}
+
+func BenchmarkRemoveLarge(b *testing.B) {
+	q := new(Deque[largeItem])
+	for i := 0; i < b.N; i++ {
+		q.PushBack(largeItem{})
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.Remove(q.Len() / 2)
+	}
+}
+
+// BenchmarkDeleteFunc removes every third item, for comparison with
+// BenchmarkCursorRemove.
+func BenchmarkDeleteFunc(b *testing.B) {
+	var q Deque[int]
+	for i := 0; i < b.N; i++ {
+		for j := range 1000 {
+			q.PushBack(j)
+		}
+		q.DeleteFunc(func(x int) bool { return x%3 == 0 })
+		q.Clear()
+	}
+}
+
 func BenchmarkYoyo(b *testing.B) {
 	var q Deque[int]
 	for i := 0; i < b.N; i++ {
@@ -1447,6 +3661,51 @@ func BenchmarkYoyo(b *testing.B) {
 	}
 }
 
+// BenchmarkYoyoShrinkPolicy compares shrink policies for a deque that is
+// repeatedly filled and emptied, and for one whose length oscillates around a
+// quarter of its capacity.
+func BenchmarkYoyoShrinkPolicy(b *testing.B) {
+	policies := []struct {
+		name   string
+		policy ShrinkPolicy
+	}{
+		{"Default", ShrinkPolicy{}},
+		{"Never", ShrinkPolicy{Never: true}},
+		{"FillRatio", ShrinkPolicy{FillRatio: 0.1}},
+		{"Delay", ShrinkPolicy{Delay: 1000}},
+		{"Adaptive", ShrinkPolicy{Adaptive: true}},
+	}
+	for _, p := range policies {
+		b.Run("Yoyo/"+p.name, func(b *testing.B) {
+			var q Deque[int]
+			q.SetShrinkPolicy(p.policy)
+			for i := 0; i < b.N; i++ {
+				for j := range 65536 {
+					q.PushBack(j)
+				}
+				for range 65536 {
+					q.PopFront()
+				}
+			}
+		})
+		b.Run("Oscillate/"+p.name, func(b *testing.B) {
+			var q Deque[int]
+			q.SetShrinkPolicy(p.policy)
+			for j := range 4097 {
+				q.PushBack(j)
+			}
+			for i := 0; i < b.N; i++ {
+				for range 2050 {
+					q.PopFront()
+				}
+				for j := range 2050 {
+					q.PushBack(j)
+				}
+			}
+		})
+	}
+}
+
 func BenchmarkYoyoFixed(b *testing.B) {
 	var q Deque[int]
 	q.SetBaseCap(64000)
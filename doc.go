@@ -17,8 +17,39 @@ The ring-buffer implementation significantly improves memory and time
 performance with fewer GC pauses, compared to implementations based on slices
 and linked lists.
 
+A resize copies all items to a new buffer. For a very large Deque, enable
+incremental resizing with SetIncrementalResize, so that items are moved to the
+new buffer a few at a time by the pushes and pops that follow a resize.
+To reuse the buffers discarded by resizes, set an Allocator such as
+PoolAllocator with SetAllocator.
+
+ChunkedDeque is an alternative for queues holding tens of millions of items. It
+stores items in fixed-size blocks, so that growing never moves existing items
+and memory is released as blocks are emptied.
+
+ExactDeque is a ring-buffer deque with capacities that are not rounded up to a
+power of two, for uses where memory matters more than the bitwise arithmetic
+that Deque relies on.
+
+SmallDeque is a Deque that stores its first few items in an array inside the
+struct, so that a deque embedded in another struct, and that stays small, does
+not allocate.
+
 For maximum speed, this deque implementation leaves concurrency safety up to
 the application to provide, however the application chooses, if needed at all.
+SyncDeque is provided for applications that need a deque that is safe for
+concurrent use, and wraps a Deque with a read-write lock.
+BlockingDeque is a concurrency-safe deque for producer/consumer pipelines,
+where removing an item waits for one to become available and adding an item to
+a deque with a capacity limit waits for space.
+
+# Bounded Deque
+
+A maximum length can be set using SetMaxLen, along with an OverflowPolicy that
+determines what happens when an item is added to a full deque. The oldest item
+at the opposite end can be evicted, making the Deque a fixed-size history
+buffer, or the new item can be rejected, or the call can panic with ErrFull.
+Evicted and rejected items are reported to the function set by SetEvictFunc.
 
 # Reading Empty Deque
 
@@ -29,6 +60,16 @@ reading from an empty deque. This is a run-time check to help catch programming
 errors, which may be missed if a second return value is ignored. Simply check
 Deque.Len() before reading from the deque.
 
+Where an empty deque or an invalid index is an expected condition, use the Try
+variants of the reading and removing methods, such as TryPopFront and TryAt.
+These return a second boolean value, instead of panicking, to indicate whether
+the operation succeeded.
+
+The values that Deque panics with are errors. A recovered panic value can be
+tested with errors.Is against ErrEmpty, ErrOutOfRange, ErrNegativeCount, and
+ErrModifiedDuringIteration, and an invalid index is reported as an *IndexError
+that can be retrieved using errors.As.
+
 # Generics
 
 Deque uses generics to create a Deque that contains items of the type
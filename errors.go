@@ -0,0 +1,51 @@
+package deque
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors used as panic values by Deque. Since Deque panics with error values,
+// a recovered value can be tested using [errors.Is] and [errors.As].
+var (
+	// ErrEmpty indicates that an element was read or removed from an empty
+	// deque.
+	ErrEmpty = errors.New("deque: empty queue")
+	// ErrOutOfRange indicates that an index was outside the range of the
+	// deque. Panics caused by an invalid index are an [*IndexError], which
+	// wraps ErrOutOfRange.
+	ErrOutOfRange = errors.New("deque: index out of range")
+	// ErrNegativeCount indicates that a negative count was given where only
+	// non-negative values are allowed.
+	ErrNegativeCount = errors.New("deque: negative count")
+	// ErrModifiedDuringIteration indicates that a deque was modified while an
+	// iterator was ranging over it.
+	ErrModifiedDuringIteration = errors.New("deque: modified during iteration")
+	// ErrFull indicates that an item was added to a deque that already holds
+	// its maximum number of items.
+	ErrFull = errors.New("deque: full")
+	// ErrBufferSize indicates that a buffer given to [NewFromBuffer] or
+	// [NewFixed] does not have a length that is a power of two.
+	ErrBufferSize = errors.New("deque: buffer length not a power of two")
+	// ErrClosed is returned by [BlockingDeque] operations after the deque has
+	// been closed.
+	ErrClosed = errors.New("deque: closed")
+)
+
+// IndexError describes an attempt to access a deque using an index that is
+// out of range.
+type IndexError struct {
+	// Index is the invalid index.
+	Index int
+	// Len is the length of the deque at the time of the access.
+	Len int
+}
+
+func (e *IndexError) Error() string {
+	return fmt.Sprintf("deque: index out of range %d with length %d", e.Index, e.Len)
+}
+
+// Unwrap returns [ErrOutOfRange].
+func (e *IndexError) Unwrap() error {
+	return ErrOutOfRange
+}
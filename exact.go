@@ -0,0 +1,374 @@
+package deque
+
+import (
+	"fmt"
+	"iter"
+)
+
+// ExactDeque is a ring-buffer deque whose capacity is not limited to powers of
+// two. Deque rounds its capacity up to a power of two so that positions in its
+// buffer can wrap using a bitwise modulus. ExactDeque instead wraps positions
+// by comparing them with the buffer size, which lets it allocate exactly the
+// capacity requested with [ExactDeque.Grow] or [ExactDeque.SetBaseCap]. For
+// example, growing an empty ExactDeque for 1,000,001 items allocates space for
+// 1,000,001 items, where Deque would allocate space for 2,097,152.
+//
+// Wrapping by comparison costs a branch where Deque uses a bitwise mask.
+// Whether that is measurable depends on the workload, and BenchmarkExactVsPow2
+// compares the two. ExactDeque provides the basic deque operations; use Deque
+// for the full method set. When the buffer is full, adding an item doubles the
+// capacity, and when only a quarter of the capacity is used, removing an item
+// halves it, but not below the base capacity.
+//
+// The zero value for ExactDeque is an empty deque ready to use.
+type ExactDeque[T any] struct {
+	buf    []T
+	head   int
+	tail   int
+	count  int
+	minCap int
+	// ver is incremented by every change to the items or to their positions
+	// in the buffer, so that iterators can detect modification.
+	ver uint
+}
+
+// Cap returns the current capacity of the deque. If q is nil, q.Cap() is zero.
+func (q *ExactDeque[T]) Cap() int {
+	if q == nil {
+		return 0
+	}
+	return len(q.buf)
+}
+
+// Len returns the number of elements currently stored in the queue. If q is
+// nil, q.Len() returns zero.
+func (q *ExactDeque[T]) Len() int {
+	if q == nil {
+		return 0
+	}
+	return q.count
+}
+
+// PushBack appends an element to the back of the queue.
+func (q *ExactDeque[T]) PushBack(elem T) {
+	q.growIfFull()
+	q.buf[q.tail] = elem
+	q.tail = q.next(q.tail)
+	q.count++
+	q.ver++
+}
+
+// PushFront prepends an element to the front of the queue.
+func (q *ExactDeque[T]) PushFront(elem T) {
+	q.growIfFull()
+	q.head = q.prev(q.head)
+	q.buf[q.head] = elem
+	q.count++
+	q.ver++
+}
+
+// PopFront removes and returns the element from the front of the queue. If the
+// queue is empty, the call panics with an error wrapping [ErrEmpty].
+func (q *ExactDeque[T]) PopFront() T {
+	if q.count <= 0 {
+		panic(fmt.Errorf("%w: PopFront() called", ErrEmpty))
+	}
+	return q.popFront()
+}
+
+// TryPopFront removes and returns the element from the front of the queue. If
+// the queue is empty, ok is false and the zero value is returned.
+func (q *ExactDeque[T]) TryPopFront() (elem T, ok bool) {
+	if q.Len() == 0 {
+		return elem, false
+	}
+	return q.popFront(), true
+}
+
+// PopBack removes and returns the element from the back of the queue. If the
+// queue is empty, the call panics with an error wrapping [ErrEmpty].
+func (q *ExactDeque[T]) PopBack() T {
+	if q.count <= 0 {
+		panic(fmt.Errorf("%w: PopBack() called", ErrEmpty))
+	}
+	return q.popBack()
+}
+
+// TryPopBack removes and returns the element from the back of the queue. If
+// the queue is empty, ok is false and the zero value is returned.
+func (q *ExactDeque[T]) TryPopBack() (elem T, ok bool) {
+	if q.Len() == 0 {
+		return elem, false
+	}
+	return q.popBack(), true
+}
+
+// Front returns the element at the front of the queue. This call panics if the
+// queue is empty.
+func (q *ExactDeque[T]) Front() T {
+	if q.count <= 0 {
+		panic(fmt.Errorf("%w: Front() called", ErrEmpty))
+	}
+	return q.buf[q.head]
+}
+
+// TryFront returns the element at the front of the queue. If the queue is
+// empty, ok is false and the zero value is returned.
+func (q *ExactDeque[T]) TryFront() (elem T, ok bool) {
+	if q.Len() == 0 {
+		return elem, false
+	}
+	return q.buf[q.head], true
+}
+
+// Back returns the element at the back of the queue. This call panics if the
+// queue is empty.
+func (q *ExactDeque[T]) Back() T {
+	if q.count <= 0 {
+		panic(fmt.Errorf("%w: Back() called", ErrEmpty))
+	}
+	return q.buf[q.prev(q.tail)]
+}
+
+// TryBack returns the element at the back of the queue. If the queue is empty,
+// ok is false and the zero value is returned.
+func (q *ExactDeque[T]) TryBack() (elem T, ok bool) {
+	if q.Len() == 0 {
+		return elem, false
+	}
+	return q.buf[q.prev(q.tail)], true
+}
+
+// At returns the element at index i in the queue without removing the element
+// from the queue. At(0) refers to the first element and is the same as
+// [ExactDeque.Front]. If the index is invalid, the call panics with an
+// [*IndexError].
+func (q *ExactDeque[T]) At(i int) T {
+	q.checkRange(i)
+	return q.buf[q.pos(i)]
+}
+
+// TryAt returns the element at index i in the queue without removing the
+// element from the queue. If the index is invalid, ok is false and the zero
+// value is returned.
+func (q *ExactDeque[T]) TryAt(i int) (elem T, ok bool) {
+	if i < 0 || i >= q.Len() {
+		return elem, false
+	}
+	return q.buf[q.pos(i)], true
+}
+
+// Set assigns the item to index i in the queue. If the index is invalid, the
+// call panics with an [*IndexError].
+func (q *ExactDeque[T]) Set(i int, item T) {
+	q.checkRange(i)
+	q.buf[q.pos(i)] = item
+	q.ver++
+}
+
+// TrySet assigns the item to index i in the queue. If the index is invalid,
+// the queue is not modified and false is returned.
+func (q *ExactDeque[T]) TrySet(i int, item T) bool {
+	if i < 0 || i >= q.Len() {
+		return false
+	}
+	q.buf[q.pos(i)] = item
+	q.ver++
+	return true
+}
+
+// Iter returns a go iterator to range over all items in the queue, yielding
+// each item from front (index 0) to back (index Len()-1). Modification of the
+// queue during iteration panics with [ErrModifiedDuringIteration].
+func (q *ExactDeque[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		ver := q.ver
+		head := q.head
+		for range q.Len() {
+			if q.ver != ver {
+				panic(ErrModifiedDuringIteration)
+			}
+			if !yield(q.buf[head]) {
+				return
+			}
+			head = q.next(head)
+		}
+	}
+}
+
+// RIter returns a reverse go iterator to range over all items in the queue,
+// yielding each item from back (index Len()-1) to front (index 0).
+// Modification of the queue during iteration panics with
+// [ErrModifiedDuringIteration].
+func (q *ExactDeque[T]) RIter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		ver := q.ver
+		tail := q.tail
+		for range q.Len() {
+			if q.ver != ver {
+				panic(ErrModifiedDuringIteration)
+			}
+			tail = q.prev(tail)
+			if !yield(q.buf[tail]) {
+				return
+			}
+		}
+	}
+}
+
+// AppendToSlice appends the items in the queue, from front to back, to the
+// given slice, and returns the resulting slice.
+func (q *ExactDeque[T]) AppendToSlice(out []T) []T {
+	if q.Len() == 0 {
+		return out
+	}
+	if q.head < q.tail {
+		return append(out, q.buf[q.head:q.tail]...)
+	}
+	out = append(out, q.buf[q.head:]...)
+	return append(out, q.buf[:q.tail]...)
+}
+
+// Clear removes all elements from the queue, but retains the current capacity.
+func (q *ExactDeque[T]) Clear() {
+	if q.Len() == 0 {
+		return
+	}
+	if q.head < q.tail {
+		clear(q.buf[q.head:q.tail])
+	} else {
+		clear(q.buf[q.head:])
+		clear(q.buf[:q.tail])
+	}
+	q.head = 0
+	q.tail = 0
+	q.count = 0
+	q.ver++
+}
+
+// Grow grows the deque's capacity, if necessary, to guarantee space for
+// another n items. If the deque must grow, its capacity becomes exactly
+// q.Len()+n, or the base capacity if that is larger. If n is negative, Grow
+// panics with an error wrapping [ErrNegativeCount].
+func (q *ExactDeque[T]) Grow(n int) {
+	if n < 0 {
+		panic(fmt.Errorf("%w: Grow(%d) called", ErrNegativeCount, n))
+	}
+	if n <= len(q.buf)-q.count {
+		return
+	}
+	q.resize(max(q.count+n, q.baseCap()))
+}
+
+// SetBaseCap sets a base capacity so that at least the specified number of
+// items can always be stored without resizing. Unlike [Deque.SetBaseCap], the
+// base capacity is not rounded up to a power of two. A baseCap of zero or less
+// restores the default base capacity.
+func (q *ExactDeque[T]) SetBaseCap(baseCap int) {
+	q.minCap = max(baseCap, 0)
+}
+
+func (q *ExactDeque[T]) checkRange(i int) {
+	if i < 0 || i >= q.count {
+		panic(&IndexError{Index: i, Len: q.Len()})
+	}
+}
+
+// pos returns the buffer position of the item at index i.
+func (q *ExactDeque[T]) pos(i int) int {
+	p := q.head + i
+	if p >= len(q.buf) {
+		p -= len(q.buf)
+	}
+	return p
+}
+
+// prev returns the previous buffer position wrapping around buffer.
+func (q *ExactDeque[T]) prev(i int) int {
+	if i == 0 {
+		i = len(q.buf)
+	}
+	return i - 1
+}
+
+// next returns the next buffer position wrapping around buffer.
+func (q *ExactDeque[T]) next(i int) int {
+	i++
+	if i == len(q.buf) {
+		return 0
+	}
+	return i
+}
+
+// popFront removes and returns the front element without checking for an
+// empty queue.
+func (q *ExactDeque[T]) popFront() T {
+	ret := q.buf[q.head]
+	var zero T
+	q.buf[q.head] = zero
+	q.head = q.next(q.head)
+	q.count--
+	q.ver++
+	q.shrinkIfExcess()
+	return ret
+}
+
+// popBack removes and returns the back element without checking for an empty
+// queue.
+func (q *ExactDeque[T]) popBack() T {
+	q.tail = q.prev(q.tail)
+	ret := q.buf[q.tail]
+	var zero T
+	q.buf[q.tail] = zero
+	q.count--
+	q.ver++
+	q.shrinkIfExcess()
+	return ret
+}
+
+// baseCap returns the base capacity, which is the default minimum capacity if
+// no base capacity is set.
+func (q *ExactDeque[T]) baseCap() int {
+	if q.minCap == 0 {
+		return minCapacity
+	}
+	return q.minCap
+}
+
+// growIfFull doubles the capacity if the buffer is full.
+func (q *ExactDeque[T]) growIfFull() {
+	if q.count != len(q.buf) {
+		return
+	}
+	q.resize(max(q.count<<1, q.baseCap()))
+}
+
+// shrinkIfExcess halves the capacity if the buffer is only a quarter full,
+// but not below the base capacity.
+func (q *ExactDeque[T]) shrinkIfExcess() {
+	if q.count<<2 <= len(q.buf) {
+		if newSize := max(q.count<<1, q.baseCap()); newSize < len(q.buf) {
+			q.resize(newSize)
+		}
+	}
+}
+
+// resize copies the items to a new buffer of exactly newSize positions.
+func (q *ExactDeque[T]) resize(newSize int) {
+	newBuf := make([]T, newSize)
+	if q.count != 0 {
+		if q.head < q.tail {
+			copy(newBuf, q.buf[q.head:q.tail])
+		} else {
+			n := copy(newBuf, q.buf[q.head:])
+			copy(newBuf[n:], q.buf[:q.tail])
+		}
+	}
+	q.buf = newBuf
+	q.head = 0
+	q.tail = q.count
+	q.ver++
+	if q.tail == newSize {
+		q.tail = 0
+	}
+}
@@ -0,0 +1,274 @@
+package deque
+
+import (
+	"errors"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestExactDequeCapacity(t *testing.T) {
+	var q ExactDeque[int]
+	q.Grow(1_000_001)
+	if q.Cap() != 1_000_001 {
+		t.Fatal("expected capacity 1000001, got", q.Cap())
+	}
+	for i := range 1_000_001 {
+		q.PushBack(i)
+	}
+	if q.Cap() != 1_000_001 {
+		t.Fatal("capacity changed while filling to capacity")
+	}
+	q.PushBack(0)
+	if q.Cap() != 2_000_002 {
+		t.Fatal("expected capacity to double, got", q.Cap())
+	}
+
+	var b ExactDeque[int]
+	b.SetBaseCap(100)
+	b.PushBack(1)
+	if b.Cap() != 100 {
+		t.Fatal("expected base capacity 100, got", b.Cap())
+	}
+	for i := range 300 {
+		b.PushFront(i)
+	}
+	if b.Cap() != 400 {
+		t.Fatal("expected capacity 400, got", b.Cap())
+	}
+	for b.Len() > 100 {
+		b.PopBack()
+	}
+	if b.Cap() != 200 {
+		t.Fatal("expected capacity 200, got", b.Cap())
+	}
+	for b.Len() != 0 {
+		b.PopFront()
+	}
+	if b.Cap() != 100 {
+		t.Fatal("expected capacity to shrink to base capacity, got", b.Cap())
+	}
+	b.Grow(5)
+	if b.Cap() != 100 {
+		t.Fatal("Grow should not resize when there is room")
+	}
+	b.SetBaseCap(0)
+	b.Grow(200)
+	if b.Cap() != 200 {
+		t.Fatal("expected capacity 200, got", b.Cap())
+	}
+
+	var nilDeque *ExactDeque[int]
+	if nilDeque.Len() != 0 || nilDeque.Cap() != 0 {
+		t.Fatal("expected zero length and capacity for nil deque")
+	}
+	assertPanics(t, "negative Grow", func() { q.Grow(-1) })
+}
+
+func TestExactDequeEmpty(t *testing.T) {
+	var q ExactDeque[string]
+	if _, ok := q.TryPopFront(); ok {
+		t.Fatal("expected TryPopFront to fail")
+	}
+	if _, ok := q.TryPopBack(); ok {
+		t.Fatal("expected TryPopBack to fail")
+	}
+	if _, ok := q.TryFront(); ok {
+		t.Fatal("expected TryFront to fail")
+	}
+	if _, ok := q.TryBack(); ok {
+		t.Fatal("expected TryBack to fail")
+	}
+	if _, ok := q.TryAt(0); ok {
+		t.Fatal("expected TryAt to fail")
+	}
+	if q.TrySet(0, "x") {
+		t.Fatal("expected TrySet to fail")
+	}
+	if err := recoverError(func() { q.PopBack() }); !errors.Is(err, ErrEmpty) {
+		t.Fatal("expected ErrEmpty, got", err)
+	}
+	assertPanics(t, "PopFront", func() { q.PopFront() })
+	assertPanics(t, "Front", func() { q.Front() })
+	assertPanics(t, "Back", func() { q.Back() })
+	var idxErr *IndexError
+	if err := recoverError(func() { q.Set(0, "x") }); !errors.As(err, &idxErr) {
+		t.Fatal("expected *IndexError, got", err)
+	}
+	assertPanics(t, "At", func() { q.At(0) })
+}
+
+func TestExactDequeRandom(t *testing.T) {
+	rng := rand.New(rand.NewPCG(13, 14))
+	var q ExactDeque[int]
+	q.SetBaseCap(10)
+	var model []int
+	var next int
+	for round := range 20000 {
+		push := rng.IntN(10) < 7
+		if (round/2000)%2 == 1 {
+			push = !push
+		}
+		switch op := rng.IntN(10); {
+		case op < 6 && push:
+			next++
+			if op%2 == 0 {
+				q.PushBack(next)
+				model = append(model, next)
+			} else {
+				q.PushFront(next)
+				model = slices.Insert(model, 0, next)
+			}
+		case op < 6:
+			if len(model) == 0 {
+				continue
+			}
+			if op%2 == 0 {
+				if x, _ := q.TryPopBack(); x != model[len(model)-1] {
+					t.Fatalf("round %d: wrong value from PopBack", round)
+				}
+				model = model[:len(model)-1]
+			} else {
+				if x, _ := q.TryPopFront(); x != model[0] {
+					t.Fatalf("round %d: wrong value from PopFront", round)
+				}
+				model = model[1:]
+			}
+		case op < 9:
+			if len(model) == 0 {
+				continue
+			}
+			i := rng.IntN(len(model))
+			if q.At(i) != model[i] {
+				t.Fatalf("round %d: wrong value at %d", round, i)
+			}
+			next++
+			q.Set(i, next)
+			model[i] = next
+		default:
+			if !slices.Equal(q.AppendToSlice(nil), model) {
+				t.Fatalf("round %d: wrong contents", round)
+			}
+			if len(model) != 0 && (q.Front() != model[0] || q.Back() != model[len(model)-1]) {
+				t.Fatalf("round %d: wrong front or back", round)
+			}
+		}
+		if q.Len() != len(model) {
+			t.Fatalf("round %d: length %d, expected %d", round, q.Len(), len(model))
+		}
+		if q.Cap() < q.Len() || q.Cap() > max(4*q.Len(), 10) {
+			t.Fatalf("round %d: capacity %d for length %d", round, q.Cap(), q.Len())
+		}
+	}
+}
+
+func TestExactDequeIter(t *testing.T) {
+	var q ExactDeque[int]
+	q.SetBaseCap(7)
+	// Wrap the items around the end of the buffer.
+	for i := range 4 {
+		q.PushBack(i)
+		q.PopFront()
+	}
+	for i := range 6 {
+		q.PushBack(i)
+	}
+	if !slices.Equal(slices.Collect(q.Iter()), []int{0, 1, 2, 3, 4, 5}) {
+		t.Fatal("wrong items from Iter")
+	}
+	if !slices.Equal(slices.Collect(q.RIter()), []int{5, 4, 3, 2, 1, 0}) {
+		t.Fatal("wrong items from RIter")
+	}
+	err := recoverError(func() {
+		for range q.Iter() {
+			q.PushFront(1)
+		}
+	})
+	if !errors.Is(err, ErrModifiedDuringIteration) {
+		t.Fatal("expected ErrModifiedDuringIteration, got", err)
+	}
+	q.Clear()
+	if q.Len() != 0 || q.Cap() != 7 {
+		t.Fatal("expected empty deque with capacity retained")
+	}
+	for _, x := range q.buf {
+		if x != 0 {
+			t.Fatal("buffer has removed items")
+		}
+	}
+}
+
+// BenchmarkExactVsPow2 compares Deque with ExactDeque, holding a number of
+// items just over a power of two, so that Deque needs almost twice the memory.
+// The slots metric is the capacity of the deque.
+func BenchmarkExactVsPow2(b *testing.B) {
+	const size = 1<<20 + 1
+	b.Run("PushPop/Deque", func(b *testing.B) {
+		var q Deque[int]
+		q.Grow(size)
+		for i := range size {
+			q.PushBack(i)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			q.PushBack(q.PopFront())
+		}
+		b.ReportMetric(float64(q.Cap()), "slots")
+	})
+	b.Run("PushPop/ExactDeque", func(b *testing.B) {
+		var q ExactDeque[int]
+		q.Grow(size)
+		for i := range size {
+			q.PushBack(i)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			q.PushBack(q.PopFront())
+		}
+		b.ReportMetric(float64(q.Cap()), "slots")
+	})
+	b.Run("At/Deque", func(b *testing.B) {
+		var q Deque[int]
+		for i := range size {
+			q.PushBack(i)
+		}
+		var sum int
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			sum += q.At(i * 7919 % size)
+		}
+		benchSum = sum
+		b.ReportMetric(float64(q.Cap()), "slots")
+	})
+	b.Run("At/ExactDeque", func(b *testing.B) {
+		var q ExactDeque[int]
+		q.Grow(size)
+		for i := range size {
+			q.PushBack(i)
+		}
+		var sum int
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			sum += q.At(i * 7919 % size)
+		}
+		benchSum = sum
+		b.ReportMetric(float64(q.Cap()), "slots")
+	})
+}
+
+func BenchmarkExactPushBack(b *testing.B) {
+	var q ExactDeque[int]
+	for i := 0; i < b.N; i++ {
+		q.PushBack(i)
+	}
+}
+
+func BenchmarkExactSerial(b *testing.B) {
+	var q ExactDeque[int]
+	for i := 0; i < b.N; i++ {
+		q.PushBack(i)
+	}
+	for i := 0; i < b.N; i++ {
+		q.PopFront()
+	}
+}
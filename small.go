@@ -0,0 +1,159 @@
+package deque
+
+import "iter"
+
+// smallSize is the number of items that a SmallDeque stores without a heap
+// allocation. Must be a power of 2, since the inline array is used as the
+// buffer of a Deque.
+const smallSize = 8
+
+// SmallDeque is a [Deque] that stores up to 8 items in an array inside the
+// SmallDeque, so that a deque that never holds more items does not allocate.
+// This suits short-lived deques, and deques embedded in other structs, that
+// usually hold only a few items. When a ninth item is added, the items are
+// moved to a buffer allocated on the heap, the inline array is cleared, and
+// from then on the SmallDeque behaves as a Deque.
+//
+// The inline array is the first buffer of the embedded Deque, unless a base
+// capacity is set with SetBaseCap, or more than 8 items are added at once.
+// Each method of SmallDeque that can give an empty deque its first buffer
+// makes the inline array available to the Deque before calling the Deque
+// method of the same name. Calling these methods through a pointer to the
+// embedded Deque bypasses this, and allocates a buffer on the heap as Deque
+// does.
+//
+// The zero value for SmallDeque is an empty deque ready to use. Since the
+// embedded Deque refers to the inline array, a SmallDeque must not be copied
+// after first use. For the same reason, a SmallDeque held in a local variable
+// is usually moved to the heap by the compiler, which costs an allocation of
+// its own.
+type SmallDeque[T any] struct {
+	Deque[T]
+	inline [smallSize]T
+	// opts is used as the options of the embedded Deque, unless other options
+	// were set first, so that using the inline array does not allocate.
+	opts options[T]
+}
+
+// PushBack appends an element to the back of the queue.
+func (q *SmallDeque[T]) PushBack(elem T) {
+	q.useInline()
+	q.Deque.PushBack(elem)
+}
+
+// PushFront prepends an element to the front of the queue.
+func (q *SmallDeque[T]) PushFront(elem T) {
+	q.useInline()
+	q.Deque.PushFront(elem)
+}
+
+// PushBackSlice appends all the items in the given slice to the back of the
+// queue, in the order they appear in the slice.
+func (q *SmallDeque[T]) PushBackSlice(items []T) {
+	q.useInline()
+	q.Deque.PushBackSlice(items)
+}
+
+// PushFrontSlice prepends all the items in the given slice to the front of the
+// queue, preserving their order.
+func (q *SmallDeque[T]) PushFrontSlice(items []T) {
+	q.useInline()
+	q.Deque.PushFrontSlice(items)
+}
+
+// PushBackSeq appends all the items from seq to the back of the queue, as with
+// [Deque.PushBackSeq].
+func (q *SmallDeque[T]) PushBackSeq(seq iter.Seq[T]) {
+	q.useInline()
+	q.Deque.PushBackSeq(seq)
+}
+
+// PushFrontSeq prepends all the items from seq to the front of the queue, as
+// with [Deque.PushFrontSeq].
+func (q *SmallDeque[T]) PushFrontSeq(seq iter.Seq[T]) {
+	q.useInline()
+	q.Deque.PushFrontSeq(seq)
+}
+
+// Insert is used to insert an element into the middle of the queue, before the
+// element at the specified index, as with [Deque.Insert].
+func (q *SmallDeque[T]) Insert(at int, item T) {
+	q.useInline()
+	q.Deque.Insert(at, item)
+}
+
+// InsertSlice inserts items into the queue before the element at the specified
+// index, as with [Deque.InsertSlice].
+func (q *SmallDeque[T]) InsertSlice(at int, items ...T) {
+	q.useInline()
+	q.Deque.InsertSlice(at, items...)
+}
+
+// Splice removes deleteCount items at index at, inserts items in their place,
+// and returns the removed items, as with [Deque.Splice].
+func (q *SmallDeque[T]) Splice(at, deleteCount int, items ...T) []T {
+	q.useInline()
+	return q.Deque.Splice(at, deleteCount, items...)
+}
+
+// Copy copies the contents of the given src Deque into this deque, as with
+// [Deque.Copy].
+func (q *SmallDeque[T]) Copy(src Deque[T]) int {
+	q.useInline()
+	return q.Deque.Copy(src)
+}
+
+// CopyInSlice replaces the contents of the deque with the items in the given
+// slice, as with [Deque.CopyInSlice].
+func (q *SmallDeque[T]) CopyInSlice(in []T) {
+	q.useInline()
+	q.Deque.CopyInSlice(in)
+}
+
+// Grow grows the deque's capacity, if necessary, to guarantee space for
+// another n items, as with [Deque.Grow]. If n is negative, Grow panics with an
+// error wrapping [ErrNegativeCount].
+func (q *SmallDeque[T]) Grow(n int) {
+	q.useInline()
+	q.Deque.Grow(n)
+}
+
+// Reserve makes space for n items at the back of the deque, and returns the
+// positions where they are to be written, as with [Deque.Reserve].
+func (q *SmallDeque[T]) Reserve(n int) (a, b []T) {
+	q.useInline()
+	return q.Deque.Reserve(n)
+}
+
+// ReserveFront makes space for n items at the front of the deque, and returns
+// the positions where they are to be written, as with [Deque.ReserveFront].
+func (q *SmallDeque[T]) ReserveFront(n int) (a, b []T) {
+	q.useInline()
+	return q.Deque.ReserveFront(n)
+}
+
+// Cursor returns a Cursor positioned before the front of the deque, as with
+// [Deque.Cursor].
+func (q *SmallDeque[T]) Cursor() Cursor[T] {
+	q.useInline()
+	return q.Deque.Cursor()
+}
+
+// RCursor returns a Cursor positioned after the back of the deque, as with
+// [Deque.RCursor].
+func (q *SmallDeque[T]) RCursor() Cursor[T] {
+	q.useInline()
+	return q.Deque.RCursor()
+}
+
+// useInline makes the inline array available as the first buffer of a deque
+// that does not yet have a buffer.
+func (q *SmallDeque[T]) useInline() {
+	if q.buf != nil || q.minCap != 0 {
+		return
+	}
+	if q.Deque.opts == nil {
+		q.Deque.opts = &q.opts
+	}
+	q.Deque.opts.ext = q.inline[:]
+}
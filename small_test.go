@@ -0,0 +1,238 @@
+package deque
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSmallDequeNoAlloc(t *testing.T) {
+	type holder struct {
+		q SmallDeque[int]
+	}
+	h := new(holder)
+	allocs := testing.AllocsPerRun(100, func() {
+		h.q = SmallDeque[int]{}
+		for i := range smallSize {
+			h.q.PushBack(i)
+		}
+		for range smallSize / 2 {
+			h.q.PopFront()
+			h.q.PushFront(-1)
+			h.q.PopBack()
+		}
+	})
+	if allocs != 0 {
+		t.Fatal("expected no allocations, got", allocs)
+	}
+	if h.q.Cap() != smallSize {
+		t.Fatal("expected capacity", smallSize, "got", h.q.Cap())
+	}
+}
+
+// TestSmallDequeFirstBuffer checks that each method that can give an empty
+// SmallDeque its first buffer uses the inline array.
+func TestSmallDequeFirstBuffer(t *testing.T) {
+	var src Deque[int]
+	src.PushBackSlice([]int{1, 2, 3})
+	adds := map[string]func(q *SmallDeque[int]){
+		"PushBack":       func(q *SmallDeque[int]) { q.PushBack(1) },
+		"PushFront":      func(q *SmallDeque[int]) { q.PushFront(1) },
+		"PushBackSlice":  func(q *SmallDeque[int]) { q.PushBackSlice([]int{1, 2}) },
+		"PushFrontSlice": func(q *SmallDeque[int]) { q.PushFrontSlice([]int{1, 2}) },
+		"Insert":         func(q *SmallDeque[int]) { q.Insert(0, 1) },
+		"InsertSlice":    func(q *SmallDeque[int]) { q.InsertSlice(0, 1, 2) },
+		"Splice":         func(q *SmallDeque[int]) { q.Splice(0, 0, 1, 2) },
+		"Copy":           func(q *SmallDeque[int]) { q.Copy(src) },
+		"CopyInSlice":    func(q *SmallDeque[int]) { q.CopyInSlice([]int{1, 2}) },
+		"Grow":           func(q *SmallDeque[int]) { q.Grow(2) },
+		"Reserve": func(q *SmallDeque[int]) {
+			q.Reserve(2)
+			q.Commit(2)
+		},
+		"ReserveFront": func(q *SmallDeque[int]) {
+			q.ReserveFront(2)
+			q.CommitFront(2)
+		},
+		"Cursor": func(q *SmallDeque[int]) {
+			c := q.Cursor()
+			c.InsertBefore(1)
+		},
+		"RCursor": func(q *SmallDeque[int]) {
+			c := q.RCursor()
+			c.InsertAfter(1)
+		},
+	}
+	h := new(struct{ q SmallDeque[int] })
+	for name, add := range adds {
+		allocs := testing.AllocsPerRun(10, func() {
+			h.q = SmallDeque[int]{}
+			add(&h.q)
+		})
+		if allocs != 0 {
+			t.Errorf("%s: expected no allocations, got %v", name, allocs)
+		}
+		if h.q.Cap() != smallSize || &h.q.buf[0] != &h.q.inline[0] {
+			t.Errorf("%s: expected items in inline array", name)
+		}
+	}
+
+	// The Seq methods collect the items into a slice before adding them.
+	seqs := map[string]func(q *SmallDeque[int]){
+		"PushBackSeq":  func(q *SmallDeque[int]) { q.PushBackSeq(slices.Values([]int{1, 2})) },
+		"PushFrontSeq": func(q *SmallDeque[int]) { q.PushFrontSeq(slices.Values([]int{1, 2})) },
+	}
+	for name, add := range seqs {
+		var q SmallDeque[int]
+		add(&q)
+		if q.Cap() != smallSize || &q.buf[0] != &q.inline[0] {
+			t.Errorf("%s: expected items in inline array", name)
+		}
+	}
+
+	// Adding more items than fit in the inline array allocates the buffer.
+	var q SmallDeque[int]
+	q.PushBackSlice(make([]int, smallSize+1))
+	if q.Cap() != minCapacity {
+		t.Fatal("expected capacity", minCapacity, "got", q.Cap())
+	}
+}
+
+func TestSmallDequeSpill(t *testing.T) {
+	var q SmallDeque[*int]
+	items := make([]*int, smallSize+1)
+	for i := range items {
+		items[i] = new(int)
+		*items[i] = i
+	}
+	q.PushBackSlice(items[1:smallSize])
+	q.PushFront(items[0])
+	if q.Cap() != smallSize || &q.buf[0] != &q.inline[0] {
+		t.Fatal("expected items in inline array")
+	}
+	q.Insert(smallSize, items[smallSize])
+	if q.Cap() != minCapacity || &q.buf[0] == &q.inline[0] {
+		t.Fatal("expected items moved to heap buffer")
+	}
+	for i, p := range q.inline {
+		if p != nil {
+			t.Fatal("inline array still refers to item", i)
+		}
+	}
+	for i := range items {
+		if q.At(i) != items[i] {
+			t.Fatal("wrong item at", i)
+		}
+	}
+	for q.Len() != 0 {
+		q.PopBack()
+	}
+	if q.Cap() != minCapacity {
+		t.Fatal("expected capacity", minCapacity, "got", q.Cap())
+	}
+}
+
+// TestSmallDequeSpillDeque checks that the inline array is cleared when the
+// items are moved to the heap by methods of the embedded Deque.
+func TestSmallDequeSpillDeque(t *testing.T) {
+	spills := map[string]func(q *SmallDeque[*int], p *int){
+		"InsertSlice": func(q *SmallDeque[*int], p *int) { q.InsertSlice(4, p, p) },
+		"Splice":      func(q *SmallDeque[*int], p *int) { q.Splice(2, 1, p, p) },
+		"Reserve":     func(q *SmallDeque[*int], p *int) { q.Reserve(2) },
+		"PushBackSeq": func(q *SmallDeque[*int], p *int) {
+			q.PushBackSeq(slices.Values([]*int{p, p}))
+		},
+		"CopyInSlice": func(q *SmallDeque[*int], p *int) {
+			q.CopyInSlice(make([]*int, 2*smallSize))
+		},
+	}
+	for name, spill := range spills {
+		var q SmallDeque[*int]
+		for i := range smallSize {
+			x := i
+			q.PushBack(&x)
+		}
+		spill(&q, new(int))
+		if &q.buf[0] == &q.inline[0] {
+			t.Fatalf("%s: expected items moved to heap buffer", name)
+		}
+		for i, p := range q.inline {
+			if p != nil {
+				t.Fatalf("%s: inline array still refers to item %d", name, i)
+			}
+		}
+	}
+}
+
+func TestSmallDequeIncremental(t *testing.T) {
+	var q SmallDeque[*int]
+	q.SetIncrementalResize(true)
+	for i := range 2 * smallSize {
+		x := i
+		q.PushBack(&x)
+	}
+	for i, p := range q.inline {
+		if p != nil {
+			t.Fatal("inline array still refers to item", i)
+		}
+	}
+	for i := range q.Len() {
+		if *q.At(i) != i {
+			t.Fatal("wrong item at", i)
+		}
+	}
+}
+
+func TestSmallDequeBaseCap(t *testing.T) {
+	var q SmallDeque[int]
+	q.SetBaseCap(64)
+	q.PushBack(1)
+	if q.Cap() != 64 {
+		t.Fatal("expected base capacity 64, got", q.Cap())
+	}
+
+	var g SmallDeque[int]
+	g.Grow(smallSize)
+	if g.Cap() != smallSize {
+		t.Fatal("expected Grow to use inline array")
+	}
+	g.Grow(smallSize + 1)
+	if g.Cap() != minCapacity {
+		t.Fatal("expected capacity", minCapacity, "got", g.Cap())
+	}
+	g.PushFrontSlice([]int{1, 2, 3})
+	if !slices.Equal(g.AppendToSlice(nil), []int{1, 2, 3}) {
+		t.Fatal("wrong contents")
+	}
+}
+
+// BenchmarkSmallDeque uses deques held in a struct, as a deque embedded in
+// another struct would be. A SmallDeque in a local variable refers to itself,
+// so it is moved to the heap.
+func BenchmarkSmallDeque(b *testing.B) {
+	b.Run("SmallDeque", func(b *testing.B) {
+		b.ReportAllocs()
+		h := new(struct{ q SmallDeque[int] })
+		for i := 0; i < b.N; i++ {
+			h.q = SmallDeque[int]{}
+			for j := range smallSize {
+				h.q.PushBack(j)
+			}
+			for h.q.Len() != 0 {
+				h.q.PopFront()
+			}
+		}
+	})
+	b.Run("Deque", func(b *testing.B) {
+		b.ReportAllocs()
+		h := new(struct{ q Deque[int] })
+		for i := 0; i < b.N; i++ {
+			h.q = Deque[int]{}
+			for j := range smallSize {
+				h.q.PushBack(j)
+			}
+			for h.q.Len() != 0 {
+				h.q.PopFront()
+			}
+		}
+	})
+}
@@ -0,0 +1,678 @@
+package deque
+
+import (
+	"iter"
+	"slices"
+	"sync"
+)
+
+// SyncDeque is a Deque that is safe for concurrent use by multiple goroutines.
+// It provides the methods of Deque, each of which is performed while holding a
+// lock. Methods that only read the deque, such as [SyncDeque.At],
+// [SyncDeque.Len], [SyncDeque.Front], [SyncDeque.Back], and [SyncDeque.Index],
+// may run concurrently with each other.
+//
+// The Deque methods that return slices of the buffer or cursors into the
+// deque, which are [Deque.AsSlices], [Deque.AsMutSlices], [Deque.MakeContiguous],
+// [Deque.Reserve], [Deque.Commit], [Deque.ReserveFront], [Deque.CommitFront],
+// [Deque.Cursor], [Deque.RCursor], and [Deque.CursorAt], are not provided,
+// since the slices and cursors they return would be used after the lock is
+// released. Use [SyncDeque.Do] to use these methods while holding the lock.
+//
+// The zero value for SyncDeque is an empty deque ready to use. A SyncDeque
+// must not be copied after first use.
+//
+// Operations made up of multiple calls to SyncDeque methods are not atomic,
+// since other goroutines may modify the deque between calls. Use [SyncDeque.Do]
+// to perform a compound operation while holding the lock:
+//
+//	var q deque.SyncDeque[int]
+//	q.Do(func(d *deque.Deque[int]) {
+//		if d.Len() != 0 && d.Front() == 0 {
+//			d.PopFront()
+//		}
+//	})
+type SyncDeque[T any] struct {
+	mu sync.RWMutex
+	d  Deque[T]
+}
+
+// Do calls f with the underlying Deque while holding an exclusive lock. This
+// allows multiple Deque operations to be performed atomically. The Deque must
+// not be retained or used after f returns, and f must not call any methods of
+// the SyncDeque.
+func (q *SyncDeque[T]) Do(f func(d *Deque[T])) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	f(&q.d)
+}
+
+// Cap returns the current capacity of the SyncDeque. If q is nil, q.Cap() is
+// zero.
+func (q *SyncDeque[T]) Cap() int {
+	if q == nil {
+		return 0
+	}
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.d.Cap()
+}
+
+// Len returns the number of elements currently stored in the queue. If q is
+// nil, q.Len() returns zero.
+func (q *SyncDeque[T]) Len() int {
+	if q == nil {
+		return 0
+	}
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.d.Len()
+}
+
+// PushBack appends an element to the back of the queue.
+func (q *SyncDeque[T]) PushBack(elem T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.PushBack(elem)
+}
+
+// PushFront prepends an element to the front of the queue.
+func (q *SyncDeque[T]) PushFront(elem T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.PushFront(elem)
+}
+
+// PushBackSlice appends all the items in the given slice to the back of the
+// queue, in the order they appear in the slice.
+func (q *SyncDeque[T]) PushBackSlice(items []T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.PushBackSlice(items)
+}
+
+// PushFrontSlice prepends all the items in the given slice to the front of the
+// queue, preserving their order.
+func (q *SyncDeque[T]) PushFrontSlice(items []T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.PushFrontSlice(items)
+}
+
+// PushBackSeq appends all the items from the given iterator to the back of
+// the queue. The items are collected before acquiring the lock, and are then
+// added together.
+func (q *SyncDeque[T]) PushBackSeq(seq iter.Seq[T]) {
+	q.PushBackSlice(slices.Collect(seq))
+}
+
+// PushFrontSeq prepends all the items from the given iterator to the front of
+// the queue, preserving the order in which they are yielded. The items are
+// collected before acquiring the lock, and are then added together.
+func (q *SyncDeque[T]) PushFrontSeq(seq iter.Seq[T]) {
+	q.PushFrontSlice(slices.Collect(seq))
+}
+
+// PopFront removes and returns the element from the front of the queue. If
+// the queue is empty, the call panics.
+func (q *SyncDeque[T]) PopFront() T {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.d.PopFront()
+}
+
+// TryPopFront removes and returns the element from the front of the queue.
+// If the queue is empty, ok is false and the zero value is returned.
+func (q *SyncDeque[T]) TryPopFront() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.d.TryPopFront()
+}
+
+// IterPopFront returns an iterator that iteratively removes items from the
+// front of the deque. Each item is removed while holding the lock, but the lock
+// is not held while the item is yielded, so the loop body may call methods of
+// the SyncDeque. If a resize is necessary, only one is done when iteration
+// ends.
+func (q *SyncDeque[T]) IterPopFront() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		defer q.shrinkToFit()
+		for {
+			q.mu.Lock()
+			if q.d.Len() == 0 {
+				q.mu.Unlock()
+				return
+			}
+			elem := q.d.popFront()
+			q.mu.Unlock()
+			if !yield(elem) {
+				return
+			}
+		}
+	}
+}
+
+// PopBack removes and returns the element from the back of the queue. If the
+// queue is empty, the call panics.
+func (q *SyncDeque[T]) PopBack() T {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.d.PopBack()
+}
+
+// TryPopBack removes and returns the element from the back of the queue. If
+// the queue is empty, ok is false and the zero value is returned.
+func (q *SyncDeque[T]) TryPopBack() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.d.TryPopBack()
+}
+
+// IterPopBack returns an iterator that iteratively removes items from the back
+// of the deque. Each item is removed while holding the lock, but the lock is
+// not held while the item is yielded, so the loop body may call methods of the
+// SyncDeque. If a resize is necessary, only one is done when iteration ends.
+func (q *SyncDeque[T]) IterPopBack() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		defer q.shrinkToFit()
+		for {
+			q.mu.Lock()
+			if q.d.Len() == 0 {
+				q.mu.Unlock()
+				return
+			}
+			elem := q.d.popBack()
+			q.mu.Unlock()
+			if !yield(elem) {
+				return
+			}
+		}
+	}
+}
+
+// PopFrontWhile returns an iterator that removes items from the front of the
+// deque while the front item satisfies pred, leaving the first item that does
+// not. Each item is tested and removed while holding the lock, so pred must not
+// call any methods of the SyncDeque. The lock is not held while the item is
+// yielded, so the loop body may call methods of the SyncDeque. If a resize is
+// necessary, only one is done when iteration ends.
+func (q *SyncDeque[T]) PopFrontWhile(pred func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		defer q.shrinkToFit()
+		for {
+			q.mu.Lock()
+			if q.d.Len() == 0 || !pred(q.d.Front()) {
+				q.mu.Unlock()
+				return
+			}
+			elem := q.d.popFront()
+			q.mu.Unlock()
+			if !yield(elem) {
+				return
+			}
+		}
+	}
+}
+
+// PopBackWhile returns an iterator that removes items from the back of the
+// deque while the back item satisfies pred, leaving the first item that does
+// not. Each item is tested and removed while holding the lock, so pred must not
+// call any methods of the SyncDeque. The lock is not held while the item is
+// yielded, so the loop body may call methods of the SyncDeque. If a resize is
+// necessary, only one is done when iteration ends.
+func (q *SyncDeque[T]) PopBackWhile(pred func(T) bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		defer q.shrinkToFit()
+		for {
+			q.mu.Lock()
+			if q.d.Len() == 0 || !pred(q.d.Back()) {
+				q.mu.Unlock()
+				return
+			}
+			elem := q.d.popBack()
+			q.mu.Unlock()
+			if !yield(elem) {
+				return
+			}
+		}
+	}
+}
+
+// PopFrontInto removes items from the front of the queue and stores them in
+// dst, until dst is full or the queue is empty. Returns the number of items
+// removed.
+func (q *SyncDeque[T]) PopFrontInto(dst []T) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.d.PopFrontInto(dst)
+}
+
+// PopBackInto removes items from the back of the queue and stores them in dst,
+// until dst is full or the queue is empty. Returns the number of items
+// removed.
+func (q *SyncDeque[T]) PopBackInto(dst []T) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.d.PopBackInto(dst)
+}
+
+// DiscardFront removes up to n items from the front of the queue without
+// returning them. Returns the number of items removed.
+func (q *SyncDeque[T]) DiscardFront(n int) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.d.DiscardFront(n)
+}
+
+// DiscardBack removes up to n items from the back of the queue without
+// returning them. Returns the number of items removed.
+func (q *SyncDeque[T]) DiscardBack(n int) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.d.DiscardBack(n)
+}
+
+// Front returns the element at the front of the queue. This call panics if
+// the queue is empty.
+func (q *SyncDeque[T]) Front() T {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.d.Front()
+}
+
+// TryFront returns the element at the front of the queue. If the queue is
+// empty, ok is false and the zero value is returned.
+func (q *SyncDeque[T]) TryFront() (T, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.d.TryFront()
+}
+
+// Back returns the element at the back of the queue. This call panics if the
+// queue is empty.
+func (q *SyncDeque[T]) Back() T {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.d.Back()
+}
+
+// TryBack returns the element at the back of the queue. If the queue is empty,
+// ok is false and the zero value is returned.
+func (q *SyncDeque[T]) TryBack() (T, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.d.TryBack()
+}
+
+// At returns the element at index i in the queue without removing the element
+// from the queue. If the index is invalid, the call panics.
+func (q *SyncDeque[T]) At(i int) T {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.d.At(i)
+}
+
+// TryAt returns the element at index i in the queue without removing the
+// element from the queue. If the index is invalid, ok is false and the zero
+// value is returned.
+func (q *SyncDeque[T]) TryAt(i int) (T, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.d.TryAt(i)
+}
+
+// Set assigns the item to index i in the queue. If the index is invalid, the
+// call panics.
+func (q *SyncDeque[T]) Set(i int, item T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.Set(i, item)
+}
+
+// TrySet assigns the item to index i in the queue. If the index is invalid,
+// the queue is not modified and false is returned.
+func (q *SyncDeque[T]) TrySet(i int, item T) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.d.TrySet(i, item)
+}
+
+// Iter returns a go iterator to range over all items in the SyncDeque,
+// yielding each item from front (index 0) to back (index Len()-1). The
+// iterator ranges over a snapshot of the items taken when iteration starts, so
+// the SyncDeque may be modified during iteration.
+func (q *SyncDeque[T]) Iter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, item := range q.AppendToSlice(nil) {
+			if !yield(item) {
+				return
+			}
+		}
+	}
+}
+
+// RIter returns a reverse go iterator to range over all items in the
+// SyncDeque, yielding each item from back (index Len()-1) to front (index 0).
+// The iterator ranges over a snapshot of the items taken when iteration
+// starts, so the SyncDeque may be modified during iteration.
+func (q *SyncDeque[T]) RIter() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		items := q.AppendToSlice(nil)
+		for i := len(items) - 1; i >= 0; i-- {
+			if !yield(items[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Values returns a go iterator that yields each item from front to back. It is
+// the same as [SyncDeque.Iter].
+func (q *SyncDeque[T]) Values() iter.Seq[T] {
+	return q.Iter()
+}
+
+// All returns a go iterator over the index and item of each item in the
+// SyncDeque, from front to back. The iterator ranges over a snapshot of the
+// items taken when iteration starts, so the SyncDeque may be modified during
+// iteration.
+func (q *SyncDeque[T]) All() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		for i, item := range q.AppendToSlice(nil) {
+			if !yield(i, item) {
+				return
+			}
+		}
+	}
+}
+
+// Backward returns a go iterator over the index and item of each item in the
+// SyncDeque, from back to front. The iterator ranges over a snapshot of the
+// items taken when iteration starts, so the SyncDeque may be modified during
+// iteration.
+func (q *SyncDeque[T]) Backward() iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		items := q.AppendToSlice(nil)
+		for i := len(items) - 1; i >= 0; i-- {
+			if !yield(i, items[i]) {
+				return
+			}
+		}
+	}
+}
+
+// IterRange returns a go iterator over the index and item of each item in the
+// SyncDeque from index from through index to-1. The iterator ranges over a
+// snapshot of the items taken when iteration starts, and panics with an
+// [*IndexError] if the range is not valid at that time.
+func (q *SyncDeque[T]) IterRange(from, to int) iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		for i, item := range q.snapshot(from, to) {
+			if !yield(from+i, item) {
+				return
+			}
+		}
+	}
+}
+
+// RIterRange returns a go iterator over the index and item of each item in the
+// SyncDeque from index to-1 down to index from. The iterator ranges over a
+// snapshot of the items taken when iteration starts, and panics with an
+// [*IndexError] if the range is not valid at that time.
+func (q *SyncDeque[T]) RIterRange(from, to int) iter.Seq2[int, T] {
+	return func(yield func(int, T) bool) {
+		items := q.snapshot(from, to)
+		for i := len(items) - 1; i >= 0; i-- {
+			if !yield(from+i, items[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Clear removes all elements from the queue, but retains the current capacity.
+func (q *SyncDeque[T]) Clear() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.Clear()
+}
+
+// Grow grows deque's capacity, if necessary, to guarantee space for another n
+// items. If n is negative, Grow panics.
+func (q *SyncDeque[T]) Grow(n int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.Grow(n)
+}
+
+// Copy copies the contents of the given src Deque into this SyncDeque.
+func (q *SyncDeque[T]) Copy(src Deque[T]) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.d.Copy(src)
+}
+
+// AppendToSlice appends from the SyncDeque to the given slice. Returns the
+// resulting slice.
+func (q *SyncDeque[T]) AppendToSlice(out []T) []T {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.d.AppendToSlice(out)
+}
+
+// CopyInSlice replaces the contents of SyncDeque with all the elements from
+// the given slice, in.
+func (q *SyncDeque[T]) CopyInSlice(in []T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.CopyInSlice(in)
+}
+
+// CopyOutSlice copies elements from the SyncDeque into the given slice, up to
+// the size of the buffer. Returns the number of elements copied.
+func (q *SyncDeque[T]) CopyOutSlice(out []T) int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.d.CopyOutSlice(out)
+}
+
+// Rotate rotates the deque n steps front-to-back. If n is negative, rotates
+// back-to-front.
+func (q *SyncDeque[T]) Rotate(n int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.Rotate(n)
+}
+
+// Index returns the index into the SyncDeque of the first item satisfying
+// f(item), or -1 if none do. The function f is called while holding a read
+// lock, and must not call any methods of the SyncDeque that modify it.
+func (q *SyncDeque[T]) Index(f func(T) bool) int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.d.Index(f)
+}
+
+// RIndex is the same as Index, but searches from Back to Front.
+func (q *SyncDeque[T]) RIndex(f func(T) bool) int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.d.RIndex(f)
+}
+
+// Insert is used to insert an element into the middle of the queue, before the
+// element at the specified index.
+func (q *SyncDeque[T]) Insert(at int, item T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.Insert(at, item)
+}
+
+// Remove removes and returns an element from the middle of the queue, at the
+// specified index. If the index is invalid, the call panics.
+func (q *SyncDeque[T]) Remove(at int) T {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.d.Remove(at)
+}
+
+// TryRemove removes and returns an element from the middle of the queue, at
+// the specified index. If the index is invalid, ok is false and the zero value
+// is returned.
+func (q *SyncDeque[T]) TryRemove(at int) (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.d.TryRemove(at)
+}
+
+// InsertSlice inserts all the items into the middle of the queue, before the
+// element at the specified index, preserving their order.
+func (q *SyncDeque[T]) InsertSlice(at int, items ...T) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.InsertSlice(at, items...)
+}
+
+// RemoveRange removes the elements from index from up to, but not including,
+// index to. If the range is invalid, the call panics. To get the removed
+// elements, use Splice(from, to-from).
+func (q *SyncDeque[T]) RemoveRange(from, to int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.RemoveRange(from, to)
+}
+
+// Splice removes deleteCount elements starting at index at, and inserts the
+// given items in their place. Returns the removed elements.
+func (q *SyncDeque[T]) Splice(at, deleteCount int, items ...T) []T {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.d.Splice(at, deleteCount, items...)
+}
+
+// DeleteFunc removes all items for which del returns true, and returns the
+// number of items removed. The function del is called while holding the lock,
+// and must not call any methods of the SyncDeque.
+func (q *SyncDeque[T]) DeleteFunc(del func(T) bool) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.d.DeleteFunc(del)
+}
+
+// Retain removes all items for which keep returns false, and returns the
+// number of items removed. The function keep is called while holding the lock,
+// and must not call any methods of the SyncDeque.
+func (q *SyncDeque[T]) Retain(keep func(T) bool) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.d.Retain(keep)
+}
+
+// RetainMut is like Retain, but calls keep with a pointer to each item, so
+// that an item can be updated as it is kept.
+func (q *SyncDeque[T]) RetainMut(keep func(item *T) bool) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.d.RetainMut(keep)
+}
+
+// SetBaseCap sets a base capacity so that at least the specified number of
+// items can always be stored without resizing.
+func (q *SyncDeque[T]) SetBaseCap(baseCap int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.SetBaseCap(baseCap)
+}
+
+// SetIncrementalResize enables or disables incremental resizing, so that no
+// single push or pop copies all the items when the deque resizes.
+func (q *SyncDeque[T]) SetIncrementalResize(enabled bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.SetIncrementalResize(enabled)
+}
+
+// SetShrinkPolicy sets the policy that determines when the deque reduces its
+// capacity as items are removed.
+func (q *SyncDeque[T]) SetShrinkPolicy(policy ShrinkPolicy) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.SetShrinkPolicy(policy)
+}
+
+// ShrinkToFit reduces the capacity of the deque to the smallest power of two
+// that holds its items, but not below the base capacity.
+func (q *SyncDeque[T]) ShrinkToFit() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.ShrinkToFit()
+}
+
+// SetMaxLen sets the maximum number of items that the deque may hold, and the
+// policy to apply when an item is added to a deque that already holds maxLen
+// items. A maxLen of zero removes the limit.
+func (q *SyncDeque[T]) SetMaxLen(maxLen int, policy OverflowPolicy) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.SetMaxLen(maxLen, policy)
+}
+
+// MaxLen returns the maximum number of items that the deque may hold, or zero
+// if there is no limit.
+func (q *SyncDeque[T]) MaxLen() int {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.d.MaxLen()
+}
+
+// SetEvictFunc sets a function that is called with each item that is evicted
+// or rejected because the deque reached its maximum length. The function is
+// called while holding the lock, and must not call any methods of the
+// SyncDeque.
+func (q *SyncDeque[T]) SetEvictFunc(f func(item T)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.SetEvictFunc(f)
+}
+
+// SetAllocator sets the allocator that provides the deque's buffers, and to
+// which buffers are returned when the deque resizes.
+func (q *SyncDeque[T]) SetAllocator(a Allocator[T]) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.SetAllocator(a)
+}
+
+// Swap exchanges the two values at idxA and idxB. It panics if either index is
+// out of range.
+func (q *SyncDeque[T]) Swap(idxA, idxB int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.Swap(idxA, idxB)
+}
+
+// TrySwap exchanges the two values at idxA and idxB. If either index is out of
+// range, the queue is not modified and false is returned.
+func (q *SyncDeque[T]) TrySwap(idxA, idxB int) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.d.TrySwap(idxA, idxB)
+}
+
+// snapshot returns a copy of the items from index from through index to-1.
+func (q *SyncDeque[T]) snapshot(from, to int) []T {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	q.d.checkSlice(from, to)
+	items := make([]T, 0, to-from)
+	for _, item := range q.d.IterRange(from, to) {
+		items = append(items, item)
+	}
+	return items
+}
+
+func (q *SyncDeque[T]) shrinkToFit() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.d.shrinkToFit()
+}
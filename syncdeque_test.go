@@ -0,0 +1,390 @@
+package deque
+
+import (
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestSyncDequeConcurrent(t *testing.T) {
+	const (
+		writers = 4
+		perG    = 1000
+	)
+	var q SyncDeque[int]
+	var wg sync.WaitGroup
+
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perG {
+				if w%2 == 0 {
+					q.PushBack(i)
+				} else {
+					q.PushFront(i)
+				}
+			}
+		}()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range perG {
+				if n := q.Len(); n != 0 {
+					q.TryAt(n - 1)
+				}
+				q.TryFront()
+				q.TryBack()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if q.Len() != writers*perG {
+		t.Fatal("q.Len() =", q.Len(), "expected", writers*perG)
+	}
+
+	var popped int
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var ok bool
+				if w%2 == 0 {
+					_, ok = q.TryPopFront()
+				} else {
+					_, ok = q.TryPopBack()
+				}
+				if !ok {
+					return
+				}
+				q.Do(func(d *Deque[int]) {
+					popped++
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	if popped != writers*perG {
+		t.Fatal("popped", popped, "expected", writers*perG)
+	}
+	if q.Len() != 0 {
+		t.Fatal("q.Len() =", q.Len(), "expected 0")
+	}
+}
+
+func TestSyncDequeNil(t *testing.T) {
+	var q *SyncDeque[int]
+	if q.Len() != 0 {
+		t.Error("expected q.Len() == 0")
+	}
+	if q.Cap() != 0 {
+		t.Error("expected q.Cap() == 0")
+	}
+}
+
+func TestSyncDequePushSlice(t *testing.T) {
+	var q SyncDeque[int]
+	q.PushBackSlice([]int{2, 3})
+	q.PushFrontSlice([]int{0, 1})
+	q.PushBackSeq(slices.Values([]int{4, 5}))
+	q.PushFrontSeq(slices.Values([]int{-2, -1}))
+	if !slices.Equal(q.AppendToSlice(nil), []int{-2, -1, 0, 1, 2, 3, 4, 5}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+}
+
+func TestSyncDequePopInto(t *testing.T) {
+	var q SyncDeque[int]
+	q.PushBackSlice([]int{0, 1, 2, 3, 4, 5, 6, 7})
+	dst := make([]int, 2)
+	if q.PopFrontInto(dst) != 2 || !slices.Equal(dst, []int{0, 1}) {
+		t.Fatal("wrong PopFrontInto result:", dst)
+	}
+	if q.PopBackInto(dst) != 2 || !slices.Equal(dst, []int{7, 6}) {
+		t.Fatal("wrong PopBackInto result:", dst)
+	}
+	if q.DiscardFront(1) != 1 || q.DiscardBack(1) != 1 {
+		t.Fatal("wrong discard count")
+	}
+	if !slices.Equal(q.AppendToSlice(nil), []int{3, 4}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+}
+
+func TestSyncDequeSplice(t *testing.T) {
+	var q SyncDeque[int]
+	q.PushBackSlice([]int{0, 1, 2, 3})
+	q.InsertSlice(2, 10, 11)
+	q.RemoveRange(0, 1)
+	if removed := q.Splice(1, 2, 20); !slices.Equal(removed, []int{10, 11}) {
+		t.Fatal("wrong removed items:", removed)
+	}
+	if !slices.Equal(q.AppendToSlice(nil), []int{1, 20, 2, 3}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+	if q.DeleteFunc(func(x int) bool { return x > 10 }) != 1 {
+		t.Fatal("wrong number of items deleted")
+	}
+	if q.Retain(func(x int) bool { return x != 2 }) != 1 {
+		t.Fatal("wrong number of items removed")
+	}
+	q.RetainMut(func(x *int) bool {
+		*x++
+		return true
+	})
+	if !slices.Equal(q.AppendToSlice(nil), []int{2, 4}) {
+		t.Fatal("wrong contents:", q.AppendToSlice(nil))
+	}
+}
+
+func TestSyncDequeDo(t *testing.T) {
+	var q SyncDeque[int]
+	q.CopyInSlice([]int{1, 2, 3})
+
+	q.Do(func(d *Deque[int]) {
+		if d.Front() == 1 {
+			d.PopFront()
+		}
+		d.PushBack(4)
+	})
+	if !slices.Equal(q.AppendToSlice(nil), []int{2, 3, 4}) {
+		t.Fatal("unexpected contents after Do:", q.AppendToSlice(nil))
+	}
+}
+
+func TestSyncDequeIter(t *testing.T) {
+	var q SyncDeque[int]
+	for i := range 10 {
+		q.PushBack(i)
+	}
+
+	// Modifying during iteration is allowed, since a snapshot is iterated.
+	var i int
+	for item := range q.Iter() {
+		if item != i {
+			t.Fatalf("index %d contains %d", i, item)
+		}
+		q.PushBack(item)
+		i++
+	}
+	if i != 10 {
+		t.Fatal("iterated over", i, "items, expected 10")
+	}
+	if q.Len() != 20 {
+		t.Fatal("q.Len() =", q.Len(), "expected 20")
+	}
+
+	i = q.Len() - 1
+	for item := range q.RIter() {
+		if item != i%10 {
+			t.Fatalf("index %d contains %d", i, item)
+		}
+		q.PopFront()
+		if i == 10 {
+			break
+		}
+		i--
+	}
+	if q.Len() != 10 {
+		t.Fatal("q.Len() =", q.Len(), "expected 10")
+	}
+
+	// The deque now holds 0 through 9.
+	for i, item := range q.All() {
+		if item != i {
+			t.Fatalf("index %d contains %d", i, item)
+		}
+		q.PushFront(-1)
+	}
+	for range 10 {
+		q.PopFront()
+	}
+	for i, item := range q.Backward() {
+		if item != i {
+			t.Fatalf("index %d contains %d", i, item)
+		}
+	}
+	if !slices.Equal(slices.Collect(q.Values()), q.AppendToSlice(nil)) {
+		t.Fatal("wrong items from Values")
+	}
+	var got []int
+	for i, item := range q.IterRange(2, 5) {
+		if item != i {
+			t.Fatalf("index %d contains %d", i, item)
+		}
+		got = append(got, item)
+		q.Clear()
+	}
+	q.PushBackSlice([]int{0, 1, 2, 3, 4, 5})
+	for _, item := range q.RIterRange(4, 6) {
+		got = append(got, item)
+	}
+	if !slices.Equal(got, []int{2, 3, 4, 5, 4}) {
+		t.Fatal("wrong items from ranges:", got)
+	}
+	assertPanics(t, "invalid range", func() {
+		for range q.IterRange(4, 7) {
+		}
+	})
+}
+
+func TestSyncDequeIterPop(t *testing.T) {
+	var q SyncDeque[int]
+	for i := range 100 {
+		q.PushBack(i)
+	}
+
+	var i int
+	for item := range q.IterPopFront() {
+		if item != i {
+			t.Fatalf("popped %d, expected %d", item, i)
+		}
+		if i == 49 {
+			break
+		}
+		i++
+	}
+	if q.Len() != 50 {
+		t.Fatal("q.Len() =", q.Len(), "expected 50")
+	}
+
+	i = 99
+	for item := range q.IterPopBack() {
+		if item != i {
+			t.Fatalf("popped %d, expected %d", item, i)
+		}
+		// Calling SyncDeque methods from loop body must not deadlock.
+		if q.Len() != i-50 {
+			t.Fatal("wrong length during IterPopBack")
+		}
+		i--
+	}
+	if q.Len() != 0 {
+		t.Fatal("q.Len() =", q.Len(), "expected 0")
+	}
+	if q.Cap() != minCapacity {
+		t.Fatal("expected buffer to shrink to minimum capacity")
+	}
+}
+
+func TestSyncDequePopWhile(t *testing.T) {
+	var q SyncDeque[int]
+	for i := range 100 {
+		q.PushBack(i)
+	}
+	var n int
+	for x := range q.PopFrontWhile(func(x int) bool { return x < 60 }) {
+		if x != n {
+			t.Fatalf("popped %d, expected %d", x, n)
+		}
+		// Calling SyncDeque methods from loop body must not deadlock.
+		q.PushBack(100 + x)
+		n++
+	}
+	if n != 60 || q.Front() != 60 {
+		t.Fatal("wrong items popped from front")
+	}
+	n = 0
+	for range q.PopBackWhile(func(x int) bool { return x >= 100 }) {
+		n++
+	}
+	if n != 60 || q.Back() != 99 || q.Len() != 40 {
+		t.Fatal("wrong items popped from back")
+	}
+}
+
+func TestSyncDequeMethods(t *testing.T) {
+	var q SyncDeque[string]
+	q.SetBaseCap(64)
+	q.SetIncrementalResize(true)
+	q.SetAllocator(PoolAllocator[string]())
+	q.SetShrinkPolicy(ShrinkPolicy{Never: true})
+	q.Grow(200)
+	q.ShrinkToFit()
+	q.Grow(40)
+	if q.Cap() != 64 {
+		t.Fatal("wrong capacity", q.Cap())
+	}
+
+	q.PushBack("b")
+	q.PushFront("a")
+	q.Insert(2, "d")
+	q.Insert(2, "c")
+	if q.Front() != "a" || q.Back() != "d" {
+		t.Fatal("wrong front or back")
+	}
+	if q.Index(func(s string) bool { return s == "c" }) != 2 {
+		t.Fatal("wrong index")
+	}
+	if q.RIndex(func(s string) bool { return s < "c" }) != 1 {
+		t.Fatal("wrong reverse index")
+	}
+	q.Swap(0, 3)
+	if !q.TrySwap(0, 3) || q.TrySwap(0, 4) {
+		t.Fatal("wrong TrySwap result")
+	}
+	q.Rotate(1)
+	if q.At(0) != "b" {
+		t.Fatal("wrong value after rotate")
+	}
+	q.Rotate(-1)
+	q.Set(1, "B")
+	if !q.TrySet(2, "C") || q.TrySet(4, "E") {
+		t.Fatal("wrong TrySet result")
+	}
+	if x, ok := q.TryAt(1); !ok || x != "B" {
+		t.Fatal("wrong value at 1")
+	}
+	if q.Remove(1) != "B" {
+		t.Fatal("wrong value removed")
+	}
+	if x, ok := q.TryRemove(1); !ok || x != "C" {
+		t.Fatal("wrong value removed")
+	}
+	if x, ok := q.TryFront(); !ok || x != "a" {
+		t.Fatal("wrong front")
+	}
+	if x, ok := q.TryBack(); !ok || x != "d" {
+		t.Fatal("wrong back")
+	}
+	if q.PopBack() != "d" || q.PopFront() != "a" {
+		t.Fatal("wrong value popped")
+	}
+	if _, ok := q.TryPopBack(); ok {
+		t.Fatal("expected empty deque")
+	}
+	assertPanics(t, "should panic when empty", func() {
+		q.Back()
+	})
+
+	var src Deque[string]
+	src.CopyInSlice([]string{"x", "y"})
+	if q.Copy(src) != 2 {
+		t.Fatal("wrong copy count")
+	}
+	out := make([]string, 3)
+	if n := q.CopyOutSlice(out); n != 2 || out[0] != "x" || out[1] != "y" {
+		t.Fatal("wrong CopyOutSlice result")
+	}
+	q.Clear()
+	if q.Len() != 0 {
+		t.Fatal("expected empty deque after Clear")
+	}
+
+	var evicted []string
+	q.SetEvictFunc(func(s string) {
+		evicted = append(evicted, s)
+	})
+	q.SetMaxLen(1, OverflowDrop)
+	if q.MaxLen() != 1 {
+		t.Fatal("wrong max length")
+	}
+	q.PushBack("a")
+	q.PushBack("b")
+	if q.Front() != "b" || !slices.Equal(evicted, []string{"a"}) {
+		t.Fatal("expected front item to be evicted")
+	}
+}